@@ -2,8 +2,9 @@ package main
 
 import (
 	"atmail/internal/config"
+	"atmail/internal/tracing"
 	"atmail/internal/wire"
-	"strconv"
+	"context"
 
 	"github.com/rifflock/lfshook"
 	"github.com/sirupsen/logrus"
@@ -16,10 +17,15 @@ import (
 // @contact.email  janemarianne.zapanta@gmail.com
 // @BasePath       /atmail
 // @securityDefinitions.basic BasicAuth
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /auth/login, sent as "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
-	envFlag := config.GetEnvVariable("ENABLE_DEBUG_LOG", "false")
-	shouldLogDebug, _ := strconv.ParseBool(envFlag)
-	if shouldLogDebug {
+	if config.GetEnvBool("ENABLE_DEBUG_LOG", false) {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
@@ -37,12 +43,42 @@ func main() {
 
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 
-	db, err := config.DB().DB()
+	shutdownTracing, err := tracing.Init(context.Background(), config.Tracing())
 	if err != nil {
-		logrus.Fatalf(err.Error())
+		logrus.Fatalf("Error initializing tracing: %s", err.Error())
 	}
-	defer db.Close()
 
-	server := wire.Initialize()
-	server.Start()
+	// Built first so an invalid setting such as STORAGE stops the server
+	// before anything touches the database
+	app, cleanup, err := wire.Initialize()
+	if err != nil {
+		logrus.Fatalf("Error initializing the server: %s", err.Error())
+	}
+
+	// With STORAGE=memory there is no database to migrate
+	if config.GetEnvBool("DB_AUTO_MIGRATE", false) && !config.Database().InMemory() {
+		migrator, closeDB, err := wire.InitializeMigrator()
+		if err != nil {
+			cleanup()
+			logrus.Fatalf("Error connecting to the database: %s", err.Error())
+		}
+		err = migrator.Up(context.Background())
+		closeDB()
+		if err != nil {
+			cleanup()
+			logrus.Fatalf("Error migrating the database: %s", err.Error())
+		}
+	}
+	if err := app.Operators.Bootstrap(context.Background()); err != nil {
+		cleanup()
+		logrus.Fatalf("Error bootstrapping operator: %s", err.Error())
+	}
+	serverErr := app.Server.Start()
+	cleanup()
+	if err := shutdownTracing(context.Background()); err != nil {
+		logrus.Errorf("Error flushing traces: %s", err.Error())
+	}
+	if serverErr != nil {
+		logrus.Fatalf("Server error: %s", serverErr.Error())
+	}
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"atmail/internal/migration"
+	"atmail/internal/wire"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up       Apply every pending migration
+  down     Roll back the most recently applied migration
+  status   List migrations and when they were applied
+  to N     Apply or roll back migrations until version N is the latest applied
+           (0 rolls back everything)
+
+The database is configured by the same environment variables as the server.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	var target uint64
+	switch command {
+	case "up", "down", "status":
+		if len(os.Args) != 2 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+	case "to":
+		var err error
+		if len(os.Args) == 3 {
+			target, err = strconv.ParseUint(os.Args[2], 10, 32)
+		}
+		if len(os.Args) != 3 || err != nil {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	migrator, cleanup, err := wire.InitializeMigrator()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to the database: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		err = migrator.To(ctx, uint(target))
+	case "status":
+		err = printStatus(ctx, migrator)
+	}
+	cleanup()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %s\n", command, err.Error())
+		os.Exit(1)
+	}
+	if command != "status" {
+		fmt.Printf("%s succeeded\n", command)
+	}
+}
+
+func printStatus(ctx context.Context, migrator *migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
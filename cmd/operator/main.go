@@ -0,0 +1,79 @@
+package main
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/wire"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: operator <command> -username <username> [-role <role>]
+
+Commands:
+  create   Create a new operator (role defaults to helpdesk)
+  rotate   Replace the password of an operator
+  role     Change the role of an operator
+  disable  Disable an operator
+
+Roles: admin, helpdesk
+
+The password is read from OPERATOR_PASSWORD or, if unset, from stdin.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	username := flags.String("username", "", "operator username")
+	role := flags.String("role", model.RoleHelpdesk, "operator role")
+	flags.Parse(os.Args[2:])
+	if *username == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	operatorService, cleanup, err := wire.InitializeOperatorService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to the database: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	switch command {
+	case "create":
+		_, err = operatorService.Create(ctx, model.OperatorRequest{Username: *username, Password: readPassword(), Role: *role})
+	case "rotate":
+		err = operatorService.RotatePassword(ctx, model.OperatorRequest{Username: *username, Password: readPassword()})
+	case "role":
+		err = operatorService.SetRole(ctx, *username, *role)
+	case "disable":
+		err = operatorService.Disable(ctx, *username)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cleanup()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %s\n", command, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("%s succeeded for operator %s\n", command, *username)
+}
+
+func readPassword() string {
+	if password, ok := os.LookupEnv("OPERATOR_PASSWORD"); ok {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimRight(password, "\r\n")
+}
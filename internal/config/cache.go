@@ -0,0 +1,18 @@
+package config
+
+// Cache-Control sent with user representations, per route. Empty sends none.
+type UserCacheConfig struct {
+	// GET /users/{id}
+	Get string
+	// GET /users
+	List string
+}
+
+// Responses depend on the caller's permissions, so by default only the
+// client may cache them and must revalidate with the ETag before reuse
+func UserCache() UserCacheConfig {
+	return UserCacheConfig{
+		Get:  GetEnvVariable("CACHE_CONTROL_USER", "private, no-cache"),
+		List: GetEnvVariable("CACHE_CONTROL_USERS", "private, no-cache"),
+	}
+}
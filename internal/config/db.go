@@ -1,54 +1,77 @@
 package config
 
 import (
-	"fmt"
-	"log"
-
-	"strconv"
+	"net"
+	"net/url"
 	"strings"
-
-	"github.com/sirupsen/logrus"
-
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"time"
 )
 
-var dbMap *gorm.DB
+// Values of STORAGE
+const (
+	StorageDatabase = "database"
+	StorageMemory   = "memory"
+)
 
-func DB() *gorm.DB {
-	if dbMap == nil {
-		dbMap = connectDatabase()
-	}
-	return dbMap
+type DBConfig struct {
+	// StorageDatabase, or StorageMemory to keep everything in process memory
+	// without connecting to a database
+	Storage string
+	// mysql, postgres or sqlite
+	Driver string
+	DSN    string
+	// Log every query
+	Log             bool
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// Limit on each repository call; zero disables it
+	QueryTimeout time.Duration
 }
 
-func connectDatabase() *gorm.DB {
-	envHasLog := GetEnvVariable("DB_HAS_LOG", "false")
-	shouldLog, _ := strconv.ParseBool(envHasLog)
-	logLevel := logger.Silent
-	if shouldLog {
-		logLevel = logger.Info
+func Database() DBConfig {
+	driver := GetEnvVariable("DB_DRIVER", "mysql")
+	// The DSN holds the password, so it is neither logged nor built when
+	// DB_DSN is given
+	dsn := GetEnvSecret("DB_DSN")
+	if dsn == "" {
+		dsn = getConnectionString(driver)
 	}
-	connString := getConnectionString()
-	var err error
-	db, err := gorm.Open(mysql.Open(connString), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
-	})
-	if err != nil {
-		logrus.Errorf("Error in connecting to db: %s", err.Error())
-		panic("Failed to connect to the database!")
+	return DBConfig{
+		Storage:         GetEnvVariable("STORAGE", StorageDatabase),
+		Driver:          driver,
+		DSN:             dsn,
+		Log:             GetEnvBool("DB_HAS_LOG", false),
+		MaxOpenConns:    GetEnvInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    GetEnvInt("DB_MAX_IDLE_CONNS", 25),
+		ConnMaxLifetime: GetEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		ConnMaxIdleTime: GetEnvDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+		QueryTimeout:    GetEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second),
 	}
+}
 
-	log.Println("Database connection established")
-	return db
+// Whether STORAGE=memory replaces the database
+func (c DBConfig) InMemory() bool {
+	return c.Storage == StorageMemory
 }
 
-func getConnectionString() string {
+func getConnectionString(driver string) string {
+	switch driver {
+	case "postgres":
+		return getPostgresConnectionString()
+	case "sqlite":
+		return getSQLiteConnectionString()
+	default:
+		return getMySQLConnectionString()
+	}
+}
+
+func getMySQLConnectionString() string {
 	var connString strings.Builder
 	connString.WriteString(GetEnvVariable("DB_USER", "user"))
 	connString.WriteString(":")
-	connString.WriteString(GetEnvVariable("DB_PASSWORD", "Password!3306"))
+	connString.WriteString(getPassword())
 	connString.WriteString("@tcp(")
 	connString.WriteString(GetEnvVariable("DB_HOST", "host.docker.internal"))
 	connString.WriteString(":")
@@ -58,6 +81,34 @@ func getConnectionString() string {
 	connString.WriteString("?charset=utf8")
 	connString.WriteString("&parseTime=True")
 	connString.WriteString("&loc=Local")
-	fmt.Println(connString.String())
 	return connString.String()
 }
+
+func getPostgresConnectionString() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(GetEnvVariable("DB_USER", "user"), getPassword()),
+		Host:     net.JoinHostPort(GetEnvVariable("DB_HOST", "host.docker.internal"), GetEnvVariable("DB_PORT", "5432")),
+		Path:     "/" + GetEnvVariable("DB_NAME", "atmail"),
+		RawQuery: url.Values{"sslmode": {GetEnvVariable("DB_SSLMODE", "disable")}}.Encode(),
+	}
+	return dsn.String()
+}
+
+func getPassword() string {
+	if password := GetEnvSecret("DB_PASSWORD"); password != "" {
+		return password
+	}
+	return "Password!3306"
+}
+
+// SQLite stores the database in the file at DB_PATH
+func getSQLiteConnectionString() string {
+	return SQLiteDSN(GetEnvVariable("DB_PATH", "atmail.db"))
+}
+
+// DSN of the SQLite database file at path, with foreign keys enforced and
+// writers waiting for each other instead of failing
+func SQLiteDSN(path string) string {
+	return path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+}
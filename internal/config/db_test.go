@@ -0,0 +1,30 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDatabase_DSN(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Setenv("DB_DRIVER", "mysql")
+	t.Setenv("DB_PASSWORD", "pw-s3cret")
+
+	t.Setenv("DB_DSN", "atmail:dsn-s3cret@tcp(db:3306)/atmail")
+	if got := Database().DSN; got != "atmail:dsn-s3cret@tcp(db:3306)/atmail" {
+		t.Errorf("Database().DSN = %q, want DB_DSN", got)
+	}
+
+	t.Setenv("DB_DSN", "")
+	if got := Database().DSN; !strings.Contains(got, ":pw-s3cret@") {
+		t.Errorf("Database().DSN = %q, want DB_PASSWORD in it", got)
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("Database() logged a secret: %s", out.String())
+	}
+}
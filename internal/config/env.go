@@ -3,11 +3,21 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
-var envConfigMap map[string]string
+var (
+	envConfigMu  sync.Mutex
+	envConfigMap map[string]string
+)
 
 func GetEnvVariable(envKey, defaultValue string) string {
+	envConfigMu.Lock()
+	defer envConfigMu.Unlock()
 	if envConfigMap == nil {
 		envConfigMap = make(map[string]string)
 	}
@@ -18,7 +28,7 @@ func GetEnvVariable(envKey, defaultValue string) string {
 	} else if v, exist := os.LookupEnv(envKey); exist && v != "" {
 		envValue := os.Getenv(envKey)
 
-		log.Printf("Saving new value in cache map for key: %s %s", envKey, envValue)
+		log.Printf("Saving new value in cache map for key: %s", envKey)
 		envConfigMap[envKey] = envValue
 		return envValue
 	} else {
@@ -28,3 +38,50 @@ func GetEnvVariable(envKey, defaultValue string) string {
 		return defaultValue
 	}
 }
+
+// Secret from the environment, such as a signing key or a password. Unlike
+// GetEnvVariable it is never logged or cached; unset is empty.
+func GetEnvSecret(envKey string) string {
+	value, _ := os.LookupEnv(envKey)
+	return value
+}
+
+func GetEnvDuration(envKey string, defaultValue time.Duration) time.Duration {
+	value := GetEnvVariable(envKey, defaultValue.String())
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Warnf("Invalid duration %q for %s, using %s", value, envKey, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func GetEnvInt(envKey string, defaultValue int) int {
+	value := GetEnvVariable(envKey, strconv.Itoa(defaultValue))
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.Warnf("Invalid number %q for %s, using %d", value, envKey, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+func GetEnvBool(envKey string, defaultValue bool) bool {
+	value := GetEnvVariable(envKey, strconv.FormatBool(defaultValue))
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Invalid boolean %q for %s, using %t", value, envKey, defaultValue)
+		return defaultValue
+	}
+	return flag
+}
+
+func GetEnvFloat(envKey string, defaultValue float64) float64 {
+	value := GetEnvVariable(envKey, strconv.FormatFloat(defaultValue, 'g', -1, 64))
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logrus.Warnf("Invalid number %q for %s, using %g", value, envKey, defaultValue)
+		return defaultValue
+	}
+	return number
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetEnvVariable_DoesNotLogValues(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Setenv("TEST_ENV_LOGGED", "s3cret-value")
+
+	if got := GetEnvVariable("TEST_ENV_LOGGED", ""); got != "s3cret-value" {
+		t.Fatalf("GetEnvVariable() = %q", got)
+	}
+	if strings.Contains(out.String(), "s3cret-value") {
+		t.Errorf("GetEnvVariable() logged the value: %s", out.String())
+	}
+}
+
+func TestGetEnvSecret(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Setenv("TEST_ENV_SECRET", "kid:s3cret")
+
+	if got := GetEnvSecret("TEST_ENV_SECRET"); got != "kid:s3cret" {
+		t.Errorf("GetEnvSecret() = %q", got)
+	}
+	if got := GetEnvSecret("TEST_ENV_SECRET_UNSET"); got != "" {
+		t.Errorf("GetEnvSecret() unset = %q", got)
+	}
+	if out.Len() != 0 {
+		t.Errorf("GetEnvSecret() logged: %s", out.String())
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+type HealthConfig struct {
+	// Limit on each readiness check
+	CheckTimeout time.Duration
+}
+
+func Health() HealthConfig {
+	return HealthConfig{
+		CheckTimeout: GetEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var signingKeys *SigningKeys
+
+// Keys used to sign and verify access tokens. Tokens are signed with the
+// active key; every configured key is accepted for verification so that a
+// new key can be activated while tokens signed with the old one are valid.
+type SigningKeys struct {
+	Method     jwt.SigningMethod
+	ActiveKID  string
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	private    map[string]interface{}
+	public     map[string]interface{}
+}
+
+func NewSigningKeys(method jwt.SigningMethod, activeKID string, private map[string]interface{}) (*SigningKeys, error) {
+	keys := &SigningKeys{
+		Method:     method,
+		ActiveKID:  activeKID,
+		Issuer:     "atmail",
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 7 * 24 * time.Hour,
+		private:    private,
+		public:     make(map[string]interface{}),
+	}
+	for kid, key := range private {
+		switch k := key.(type) {
+		case []byte:
+			keys.public[kid] = k
+		case crypto.Signer:
+			keys.public[kid] = k.Public()
+		default:
+			return nil, fmt.Errorf("unsupported key type for kid %s", kid)
+		}
+	}
+	if _, ok := private[activeKID]; !ok {
+		return nil, fmt.Errorf("active key %s is not configured", activeKID)
+	}
+	return keys, nil
+}
+
+// Key and kid that new tokens are signed with
+func (k *SigningKeys) SigningKey() (string, interface{}) {
+	return k.ActiveKID, k.private[k.ActiveKID]
+}
+
+// Key that verifies tokens carrying the given kid
+func (k *SigningKeys) VerificationKey(kid string) (interface{}, bool) {
+	key, ok := k.public[kid]
+	return key, ok
+}
+
+func JWTSigningKeys() *SigningKeys {
+	if signingKeys == nil {
+		keys, err := loadSigningKeys()
+		if err != nil {
+			logrus.Errorf("Error in loading JWT signing keys: %s", err.Error())
+			panic("Failed to load the JWT signing keys!")
+		}
+		signingKeys = keys
+	}
+	return signingKeys
+}
+
+// JWT_KEYS is a comma separated list of kid:key pairs. For HS256 the key is
+// the shared secret, for RS256 and EdDSA it is the path of a PEM private key.
+func loadSigningKeys() (*SigningKeys, error) {
+	method := jwt.GetSigningMethod(GetEnvVariable("JWT_SIGNING_METHOD", "HS256"))
+	if method == nil {
+		return nil, errors.New("unsupported JWT_SIGNING_METHOD")
+	}
+
+	private := make(map[string]interface{})
+	var kids []string
+	for _, entry := range strings.Split(GetEnvSecret("JWT_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, value, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || value == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q", entry)
+		}
+		key, err := parseSigningKey(method, value)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		private[kid] = key
+		kids = append(kids, kid)
+	}
+
+	if len(kids) == 0 {
+		if method != jwt.SigningMethodHS256 {
+			return nil, errors.New("JWT_KEYS is required for asymmetric signing methods")
+		}
+		logrus.Warnln("JWT_KEYS is not set, using a random secret; tokens will not survive a restart")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		private["ephemeral"] = secret
+		kids = append(kids, "ephemeral")
+	}
+
+	keys, err := NewSigningKeys(method, GetEnvVariable("JWT_ACTIVE_KID", kids[0]), private)
+	if err != nil {
+		return nil, err
+	}
+	keys.Issuer = GetEnvVariable("JWT_ISSUER", keys.Issuer)
+	if keys.AccessTTL, err = time.ParseDuration(GetEnvVariable("JWT_ACCESS_TTL", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid JWT_ACCESS_TTL: %w", err)
+	}
+	if keys.RefreshTTL, err = time.ParseDuration(GetEnvVariable("JWT_REFRESH_TTL", "168h")); err != nil {
+		return nil, fmt.Errorf("invalid JWT_REFRESH_TTL: %w", err)
+	}
+	return keys, nil
+}
+
+func parseSigningKey(method jwt.SigningMethod, value string) (interface{}, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(value), nil
+	case *jwt.SigningMethodRSA:
+		pem, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		pem, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.ParseEdPrivateKeyFromPEM(pem)
+	default:
+		return nil, errors.New("unsupported signing method")
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+type ServerConfig struct {
+	Address           string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// Largest request body accepted
+	MaxBodyBytes    int64
+	ShutdownTimeout time.Duration
+	// Addresses or CIDR ranges of proxies whose X-Forwarded-For and
+	// X-Real-IP headers are believed. None by default.
+	TrustedProxies []string
+	TLS            TLSConfig
+}
+
+// HTTPS is served when CertFile and KeyFile are set
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// CA bundle used to verify client certificates
+	ClientCAFile string
+	// none, optional or require
+	ClientAuth string
+	// Address of a plain HTTP listener that redirects to HTTPS. Plain HTTP
+	// is refused when empty.
+	RedirectAddress string
+	ReloadInterval  time.Duration
+}
+
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+func Server() ServerConfig {
+	return ServerConfig{
+		Address:           GetEnvVariable("SERVER_ADDRESS", ":"+GetEnvVariable("SERVER_PORT", "80")),
+		ReadTimeout:       GetEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout: GetEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      GetEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       GetEnvDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    GetEnvInt("SERVER_MAX_HEADER_BYTES", 1<<20),
+		MaxBodyBytes:      int64(GetEnvInt("SERVER_MAX_BODY_BYTES", 1<<20)),
+		ShutdownTimeout:   GetEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		TrustedProxies:    splitList(GetEnvVariable("SERVER_TRUSTED_PROXIES", "")),
+		TLS: TLSConfig{
+			CertFile:        GetEnvVariable("TLS_CERT_FILE", ""),
+			KeyFile:         GetEnvVariable("TLS_KEY_FILE", ""),
+			ClientCAFile:    GetEnvVariable("TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:      GetEnvVariable("TLS_CLIENT_AUTH", "none"),
+			RedirectAddress: GetEnvVariable("TLS_HTTP_REDIRECT_ADDRESS", ""),
+			ReloadInterval:  GetEnvDuration("TLS_RELOAD_INTERVAL", 10*time.Second),
+		},
+	}
+}
+
+// Non-empty entries of a comma separated list
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,19 @@
+package config
+
+type TracingConfig struct {
+	// none, stdout or otlp. The OTLP exporter reads its endpoint and headers
+	// from the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter    string
+	ServiceName string
+	// Fraction of new traces that are sampled. Requests that carry a
+	// traceparent follow the caller's decision.
+	SampleRatio float64
+}
+
+func Tracing() TracingConfig {
+	return TracingConfig{
+		Exporter:    GetEnvVariable("TRACING_EXPORTER", "none"),
+		ServiceName: GetEnvVariable("OTEL_SERVICE_NAME", "atmail"),
+		SampleRatio: GetEnvFloat("TRACING_SAMPLE_RATIO", 1),
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+// How deleted users are handled
+type UserDeletionConfig struct {
+	// Whether deleted users keep their email and username from being taken
+	// by other users until they are purged, so that they can be restored
+	ReserveValues bool
+}
+
+// How changes to existing users guard against lost updates
+type UserConcurrencyConfig struct {
+	// Whether PUT, PATCH, DELETE and restore must send If-Match
+	RequireIfMatch bool
+}
+
+func UserConcurrency() UserConcurrencyConfig {
+	return UserConcurrencyConfig{
+		RequireIfMatch: GetEnvBool("SERVER_REQUIRE_IF_MATCH", false),
+	}
+}
+
+func UserDeletion() UserDeletionConfig {
+	return UserDeletionConfig{
+		ReserveValues: GetEnvBool("USERS_DELETED_RESERVE_VALUES", true),
+	}
+}
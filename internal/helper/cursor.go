@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Cursor marks the last row of a page for keyset pagination
+type Cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    uint        `json:"i"`
+}
+
+func EncodeCursor(c Cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	// Values are compared with columns, so only scalars make sense
+	switch c.Value.(type) {
+	case nil, string, float64, bool:
+	default:
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// Character that escapes wildcards in LIKE patterns. It is given explicitly
+// with ESCAPE because SQLite has no default and MySQL and PostgreSQL disagree
+// on how a backslash is written in a string literal.
+const LikeEscape = "!"
+
+// Escapes the wildcard characters of a LIKE pattern
+func EscapeLike(s string) string {
+	replacer := strings.NewReplacer(LikeEscape, LikeEscape+LikeEscape, `%`, LikeEscape+`%`, `_`, LikeEscape+`_`)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"atmail/internal/helper"
+	"atmail/internal/http/middleware"
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const REVOKED = "Successfully revoked"
+
+type ApiKeyHandler struct {
+	apiKeyService service.ApiKeyService
+}
+
+func NewApiKeyHandler(service service.ApiKeyService) ApiKeyHandler {
+	return ApiKeyHandler{
+		apiKeyService: service,
+	}
+}
+
+// @Summary      Create API key
+// @Description  Mint an API key. The key is only returned in this response.
+// @Tags         API Keys
+// @Id           CreateApiKey
+// @Accept       json
+// @Produce      json
+// @Param        Body  body  model.ApiKeyRequest  true  "API key details"
+// @Router       /api-keys [post]
+// @Success      201 {object} model.ApiKeyCreated
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+func (a *ApiKeyHandler) Create(ctx *gin.Context) {
+	log.Infoln("Creating API key...")
+	var req model.ApiKeyRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	key, err := a.apiKeyService.Create(ctx.Request.Context(), req, middleware.GetPrincipal(ctx))
+	if err != nil {
+		log.Debugf("Error creating API key: %+v %+v", err.Error(), req)
+		errorResponse(ctx, err)
+		return
+	}
+	log.Infof("Successfully created API key %s.", key.Prefix)
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// @Summary      Retrieve all API keys
+// @Description  Retrieve all API keys without their secrets
+// @Tags         API Keys
+// @Id           GetAllApiKeys
+// @Produce      json
+// @Router       /api-keys [get]
+// @Success      200 {array} model.ApiKey
+// @Failure      403 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+func (a *ApiKeyHandler) GetAll(ctx *gin.Context) {
+	log.Infoln("Retrieving all API keys...")
+	keys, err := a.apiKeyService.GetAll(ctx.Request.Context())
+	if err != nil {
+		log.Debugf("Error retrieving API keys: %s", err.Error())
+		errorResponse(ctx, err)
+		return
+	}
+	log.Infoln("Done retrieving all API keys.")
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// @Summary      Revoke API key
+// @Description  Revoke an API key
+// @Tags         API Keys
+// @Id           RevokeApiKey
+// @Produce      json
+// @Param        id  path  string true "API key ID"
+// @Router       /api-keys/{id} [delete]
+// @Success      200 string string
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+func (a *ApiKeyHandler) Revoke(ctx *gin.Context) {
+	log.Infoln("Revoking API key...")
+	id, err := helper.CleanID(ctx.Param("id"))
+	if err != nil {
+		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.apiKeyService.Revoke(ctx.Request.Context(), *id); err != nil {
+		log.Debugf("Error revoking API key: %s", err.Error())
+		errorResponse(ctx, err)
+		return
+	}
+	log.Infoln("Successfully revoked API key.")
+	ctx.JSON(http.StatusOK, REVOKED)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	mock_service "atmail/internal/mock"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+)
+
+func TestApiKeyHandler_Create(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		httpStatus int
+		err        error
+	}{
+		{name: "Create key successfully", body: `{"name":"jobs","scopes":["users:read"]}`, httpStatus: 201},
+		{name: "Invalid scope", body: `{"name":"jobs","scopes":["users:all"]}`, httpStatus: 400, err: &service.ValidationError{Errors: []service.FieldError{{Field: "scopes", Code: "scope_invalid", Message: "invalid scope: users:all"}}}},
+		{name: "Storage failure", body: `{"name":"jobs","scopes":["users:read"]}`, httpStatus: 500, err: errors.New("database is locked")},
+		{name: "Malformed body", body: `{`, httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockApiKeyService(ctrl)
+			if tt.body != `{` {
+				var created *model.ApiKeyCreated
+				if tt.err == nil {
+					created = &model.ApiKeyCreated{ApiKey: model.ApiKey{ID: 1, Name: "jobs"}, Key: "atm_abc_def"}
+				}
+				serviceMock.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(created, tt.err).Times(1)
+			}
+
+			handler := NewApiKeyHandler(serviceMock)
+			router := gin.New()
+			router.POST("/api-keys", handler.Create)
+
+			req, err := http.NewRequest(http.MethodPost, "/api-keys", bytes.NewBufferString(tt.body))
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/json")
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
+
+func TestApiKeyHandler_Revoke(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		httpStatus int
+		err        error
+	}{
+		{name: "Revoke key successfully", id: "1", httpStatus: 200},
+		{name: "Key not found", id: "100", httpStatus: 404, err: service.ErrApiKeyNotFound},
+		{name: "Invalid ID", id: "abc", httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockApiKeyService(ctrl)
+			if tt.httpStatus != 400 {
+				serviceMock.EXPECT().Revoke(gomock.Any(), gomock.Any()).Return(tt.err).Times(1)
+			}
+
+			handler := NewApiKeyHandler(serviceMock)
+			router := gin.New()
+			router.DELETE("/api-keys/:id", handler.Revoke)
+
+			req, err := http.NewRequest(http.MethodDelete, "/api-keys/"+tt.id, nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"atmail/internal/helper"
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(service service.AuditService) AuditHandler {
+	return AuditHandler{
+		auditService: service,
+	}
+}
+
+// @Summary      Retrieve the audit log
+// @Description  Retrieve a page of changes to users, newest first
+// @Tags         Audit
+// @Id           GetAudit
+// @Produce      json
+// @Param        limit       query  int     false  "Page size (1-100, default 20)"
+// @Param        cursor      query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        user_id     query  int     false  "Only changes to this user"
+// @Param        action      query  string  false  "Only this kind of change"  Enums(create, update, delete, restore, purge)
+// @Param        actor       query  string  false  "Only changes made by this principal, such as operator:1"
+// @Param        request_id  query  string  false  "Only changes made by this request"
+// @Param        from        query  string  false  "Only changes at or after this RFC 3339 time"
+// @Param        to          query  string  false  "Only changes before this RFC 3339 time"
+// @Router       /audit [get]
+// @Success      200 {object} model.UserAuditList
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (a *AuditHandler) List(ctx *gin.Context) {
+	log.Infoln("Retrieving audit log...")
+	var query model.AuditQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		log.Debugf("Validation failed: %+v", err.Error())
+		problem.Respond(ctx, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+	a.respond(ctx, query)
+}
+
+// @Summary      Retrieve user history
+// @Description  Retrieve a page of changes to a user, newest first. Purged users keep their history.
+// @Tags         Audit
+// @Id           GetUserHistory
+// @Produce      json
+// @Param        id          path   string  true   "User ID"
+// @Param        limit       query  int     false  "Page size (1-100, default 20)"
+// @Param        cursor      query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        action      query  string  false  "Only this kind of change"  Enums(create, update, delete, restore, purge)
+// @Param        actor       query  string  false  "Only changes made by this principal, such as operator:1"
+// @Param        request_id  query  string  false  "Only changes made by this request"
+// @Param        from        query  string  false  "Only changes at or after this RFC 3339 time"
+// @Param        to          query  string  false  "Only changes before this RFC 3339 time"
+// @Router       /users/{id}/history [get]
+// @Success      200 {object} model.UserAuditList
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (a *AuditHandler) History(ctx *gin.Context) {
+	log.Infoln("Retrieving user history...")
+	id, err := helper.CleanID(ctx.Param("id"))
+	if err != nil {
+		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	var query model.AuditQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		log.Debugf("Validation failed: %+v", err.Error())
+		problem.Respond(ctx, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+	query.UserID = id
+	a.respond(ctx, query)
+}
+
+func (a *AuditHandler) respond(ctx *gin.Context, query model.AuditQuery) {
+	entries, err := a.auditService.List(ctx.Request.Context(), query)
+	if err != nil {
+		log.Debugf("Error retrieving audit log: %+v", err.Error())
+		errorResponse(ctx, err)
+		return
+	}
+	entries.Next = nextPageURI(ctx, entries.NextCursor)
+	log.Infoln("Done retrieving audit log.")
+	ctx.JSON(http.StatusOK, entries)
+}
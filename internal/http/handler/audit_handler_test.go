@@ -0,0 +1,75 @@
+package handler
+
+import (
+	mock_service "atmail/internal/mock"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+)
+
+func TestAuditHandler_List(t *testing.T) {
+	userID := uint(7)
+	tests := []struct {
+		name       string
+		path       string
+		wantQuery  *model.AuditQuery
+		list       *model.UserAuditList
+		httpStatus int
+		wantNext   string
+		err        error
+	}{
+		{name: "List audit log", path: "/audit?limit=1&action=update", httpStatus: 200,
+			wantQuery: &model.AuditQuery{Limit: 1, Action: model.AuditUpdate},
+			list:      &model.UserAuditList{Items: []model.UserAuditEntry{{ID: 2, UserID: 7, Action: model.AuditUpdate}}, NextCursor: "abc"},
+			wantNext:  "/audit?action=update&cursor=abc&limit=1"},
+		{name: "User history", path: "/users/7/history?user_id=8", httpStatus: 200,
+			wantQuery: &model.AuditQuery{UserID: &userID},
+			list:      &model.UserAuditList{Items: []model.UserAuditEntry{}}},
+		{name: "Invalid user id", path: "/users/abc/history", httpStatus: 400},
+		{name: "Invalid time", path: "/audit?from=yesterday", httpStatus: 400},
+		{name: "Invalid action", path: "/audit?action=read", httpStatus: 400,
+			err: &service.ValidationError{Errors: []service.FieldError{{Field: "action", Code: "action_invalid", Message: "invalid audit action"}}}},
+		{name: "Database failure", path: "/audit", httpStatus: 500, err: &service.InternalError{Err: errors.New("connection refused")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockAuditService(ctrl)
+			if tt.list != nil || tt.err != nil {
+				query := gomock.Any()
+				if tt.wantQuery != nil {
+					query = gomock.Eq(*tt.wantQuery)
+				}
+				serviceMock.EXPECT().List(gomock.Any(), query).Return(tt.list, tt.err).Times(1)
+			}
+
+			handler := NewAuditHandler(serviceMock)
+			router := gin.New()
+			router.GET("/audit", handler.List)
+			router.GET("/users/:id/history", handler.History)
+
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.list != nil {
+				var got model.UserAuditList
+				g.Expect(json.Unmarshal(writer.Body.Bytes(), &got)).To(gomega.Succeed())
+				g.Expect(got.Next).To(gomega.Equal(tt.wantNext))
+				g.Expect(got.Items).To(gomega.HaveLen(len(tt.list.Items)))
+			}
+		})
+	}
+}
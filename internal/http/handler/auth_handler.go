@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type AuthHandler struct {
+	tokenService service.TokenService
+}
+
+func NewAuthHandler(service service.TokenService) AuthHandler {
+	return AuthHandler{
+		tokenService: service,
+	}
+}
+
+// @Summary      Login
+// @Description  Exchange operator credentials for an access token and a refresh token
+// @Tags         Auth
+// @Id           Login
+// @Accept       json
+// @Produce      json
+// @Param        Body  body  model.LoginRequest  true  "Operator credentials"
+// @Router       /auth/login [post]
+// @Success      200 {object} model.Token
+// @Failure      400 {object} model.Problem
+// @Failure      401 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+func (a *AuthHandler) Login(ctx *gin.Context) {
+	log.Infoln("Logging in...")
+	var req model.LoginRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	token, err := a.tokenService.Login(ctx.Request.Context(), req)
+	if err != nil {
+		a.handleError(ctx, err)
+		return
+	}
+	log.Infoln("Successfully logged in.")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// @Summary      Refresh
+// @Description  Exchange a refresh token for a new access token and refresh token
+// @Tags         Auth
+// @Id           Refresh
+// @Accept       json
+// @Produce      json
+// @Param        Body  body  model.RefreshRequest  true  "Refresh token"
+// @Router       /auth/refresh [post]
+// @Success      200 {object} model.Token
+// @Failure      400 {object} model.Problem
+// @Failure      401 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+func (a *AuthHandler) Refresh(ctx *gin.Context) {
+	log.Infoln("Refreshing token...")
+	var req model.RefreshRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		problem.Respond(ctx, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	token, err := a.tokenService.Refresh(ctx.Request.Context(), req)
+	if err != nil {
+		a.handleError(ctx, err)
+		return
+	}
+	log.Infoln("Successfully refreshed token.")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// @Summary      Logout
+// @Description  Revoke a refresh token
+// @Tags         Auth
+// @Id           Logout
+// @Accept       json
+// @Produce      json
+// @Param        Body  body  model.RefreshRequest  true  "Refresh token"
+// @Router       /auth/logout [post]
+// @Success      204
+// @Failure      400 {object} model.Problem
+// @Failure      401 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+func (a *AuthHandler) Logout(ctx *gin.Context) {
+	log.Infoln("Logging out...")
+	var req model.RefreshRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		problem.Respond(ctx, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	if err := a.tokenService.Logout(ctx.Request.Context(), req); err != nil {
+		a.handleError(ctx, err)
+		return
+	}
+	log.Infoln("Successfully logged out.")
+	ctx.Status(http.StatusNoContent)
+}
+
+func (a *AuthHandler) handleError(ctx *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrInvalidToken) {
+		problem.Respond(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+	log.Errorf("Error handling auth request: %s", err.Error())
+	problem.Respond(ctx, http.StatusInternalServerError, "")
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	mock_service "atmail/internal/mock"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+)
+
+func TestAuthHandler_Login(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		httpStatus int
+		token      *model.Token
+		err        error
+	}{
+		{name: "Login successfully", body: `{"username":"admin","password":"secret"}`, httpStatus: 200, token: &model.Token{AccessToken: "a", RefreshToken: "r"}},
+		{name: "Invalid credentials", body: `{"username":"admin","password":"wrong"}`, httpStatus: 401, err: service.ErrInvalidCredentials},
+		{name: "Database error", body: `{"username":"admin","password":"secret"}`, httpStatus: 500, err: errors.New("connection refused")},
+		{name: "Malformed body", body: `{`, httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockTokenService(ctrl)
+			if tt.token != nil || tt.err != nil {
+				serviceMock.EXPECT().Login(gomock.Any(), gomock.Any()).Return(tt.token, tt.err).Times(1)
+			}
+
+			handler := NewAuthHandler(serviceMock)
+			router := gin.New()
+			router.POST("/auth/login", handler.Login)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(tt.body))
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/json")
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
+
+func TestAuthHandler_RefreshAndLogout(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		httpStatus int
+		err        error
+	}{
+		{name: "Refresh successfully", path: "/auth/refresh", body: `{"refresh_token":"r"}`, httpStatus: 200},
+		{name: "Refresh with revoked token", path: "/auth/refresh", body: `{"refresh_token":"r"}`, httpStatus: 401, err: service.ErrInvalidToken},
+		{name: "Refresh without token", path: "/auth/refresh", body: `{}`, httpStatus: 400},
+		{name: "Logout successfully", path: "/auth/logout", body: `{"refresh_token":"r"}`, httpStatus: 204},
+		{name: "Logout with unknown token", path: "/auth/logout", body: `{"refresh_token":"r"}`, httpStatus: 401, err: service.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockTokenService(ctrl)
+			if tt.httpStatus != 400 {
+				if tt.path == "/auth/refresh" {
+					serviceMock.EXPECT().Refresh(gomock.Any(), gomock.Any()).Return(&model.Token{}, tt.err).Times(1)
+				} else {
+					serviceMock.EXPECT().Logout(gomock.Any(), gomock.Any()).Return(tt.err).Times(1)
+				}
+			}
+
+			handler := NewAuthHandler(serviceMock)
+			router := gin.New()
+			router.POST("/auth/refresh", handler.Refresh)
+			router.POST("/auth/logout", handler.Logout)
+
+			req, err := http.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/json")
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
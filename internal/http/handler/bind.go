@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"atmail/internal/helper"
+	"atmail/internal/http/problem"
+	"atmail/internal/jsonpatch"
+	"atmail/internal/model"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Decode a JSON request body into dst, rejecting other content types,
+// malformed or oversized bodies, unknown fields and values of the wrong
+// type. On failure the problem has been written and false is returned.
+func bindJSON(ctx *gin.Context, dst interface{}) bool {
+	mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		problem.Respond(ctx, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(dst)
+	if err == nil && decoder.Decode(&struct{}{}) != io.EOF {
+		err = errTrailingData
+	}
+	if err == nil {
+		return true
+	}
+	log.Debugf("Invalid request body: %s", err.Error())
+
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		tooLargeErr *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &tooLargeErr):
+		problem.Respond(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLargeErr.Limit))
+	case errors.Is(err, io.EOF):
+		problem.Respond(ctx, http.StatusBadRequest, "request body is required")
+	case errors.As(err, &syntaxErr):
+		problem.Respond(ctx, http.StatusBadRequest, fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		problem.Respond(ctx, http.StatusBadRequest, "malformed JSON: unexpected end of body")
+	case errors.Is(err, errTrailingData):
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		invalidBody(ctx, model.FieldError{Field: typeErr.Field, Code: "type_invalid", Message: typeErr.Field + " must be " + helper.JSONTypeName(typeErr.Type)})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if unquoteErr != nil {
+			field = strings.TrimPrefix(err.Error(), "json: unknown field ")
+		}
+		invalidBody(ctx, model.FieldError{Field: field, Code: "field_unknown", Message: "unknown field " + field})
+	default:
+		problem.Respond(ctx, http.StatusBadRequest, "request body must be a JSON object")
+	}
+	return false
+}
+
+// Read a JSON Merge Patch or JSON Patch body. On failure the problem has been
+// written and false is returned.
+func bindPatch(ctx *gin.Context) (*model.UserPatch, bool) {
+	mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	if err != nil || (mediaType != jsonpatch.MergePatchType && mediaType != jsonpatch.JSONPatchType) {
+		ctx.Header("Accept-Patch", acceptPatch)
+		problem.Respond(ctx, http.StatusUnsupportedMediaType, "Content-Type must be "+jsonpatch.MergePatchType+" or "+jsonpatch.JSONPatchType)
+		return nil, false
+	}
+
+	document, err := io.ReadAll(ctx.Request.Body)
+	var tooLargeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLargeErr):
+		problem.Respond(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLargeErr.Limit))
+		return nil, false
+	case err != nil:
+		log.Debugf("Error reading request body: %s", err.Error())
+		problem.Respond(ctx, http.StatusBadRequest, "request body could not be read")
+		return nil, false
+	case len(bytes.TrimSpace(document)) == 0:
+		problem.Respond(ctx, http.StatusBadRequest, "request body is required")
+		return nil, false
+	}
+	return &model.UserPatch{MediaType: mediaType, Document: document}, true
+}
+
+// Patch media types accepted, advertised with the Accept-Patch header
+var acceptPatch = jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+
+var errTrailingData = errors.New("request body must contain a single JSON object")
+
+func invalidBody(ctx *gin.Context, fieldErr model.FieldError) {
+	p := problem.New(ctx, http.StatusBadRequest, fieldErr.Message)
+	p.Type, p.Title, p.Errors = model.ProblemTypeValidation, "Invalid request", []model.FieldError{fieldErr}
+	problem.Write(ctx, p)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"atmail/internal/http/middleware"
+	"atmail/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestBindJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		httpStatus  int
+		field       string
+		code        string
+	}{
+		{name: "Valid body", contentType: "application/json", body: `{"username":"username1","email":"email1@gmail.com","age":30}`, httpStatus: 200},
+		{name: "Charset parameter is accepted", contentType: "application/json; charset=utf-8", body: `{"age":30}`, httpStatus: 200},
+		{name: "Missing Content-Type", body: `{"age":30}`, httpStatus: 415},
+		{name: "Form Content-Type", contentType: "application/x-www-form-urlencoded", body: `age=30`, httpStatus: 415},
+		{name: "Empty body", contentType: "application/json", body: ``, httpStatus: 400},
+		{name: "Malformed JSON", contentType: "application/json", body: `{"age":30,}`, httpStatus: 400},
+		{name: "Truncated JSON", contentType: "application/json", body: `{"age":30`, httpStatus: 400},
+		{name: "Not an object", contentType: "application/json", body: `[1,2]`, httpStatus: 400},
+		{name: "Trailing data", contentType: "application/json", body: `{"age":30}{"age":31}`, httpStatus: 400},
+		{name: "Unknown field", contentType: "application/json", body: `{"id":5,"age":30}`, httpStatus: 400, field: "id", code: "field_unknown"},
+		{name: "Wrong type", contentType: "application/json", body: `{"age":"thirty"}`, httpStatus: 400, field: "age", code: "type_invalid"},
+		{name: "Too large", contentType: "application/json", body: `{"username":"` + strings.Repeat("a", 100) + `"}`, httpStatus: 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			router := gin.New()
+			router.Use(middleware.BodyLimit(64))
+			router.POST("/bind", func(ctx *gin.Context) {
+				var req model.UserUpdateRequest
+				if !bindJSON(ctx, &req) {
+					return
+				}
+				ctx.JSON(http.StatusOK, req)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader(tt.body))
+			// Exercise the limit while reading rather than the Content-Length check
+			req.ContentLength = -1
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.httpStatus == http.StatusOK {
+				return
+			}
+			g.Expect(writer.Header().Get("Content-Type")).To(gomega.Equal("application/problem+json"))
+			var body model.Problem
+			g.Expect(json.Unmarshal(writer.Body.Bytes(), &body)).To(gomega.Succeed())
+			g.Expect(body.Status).To(gomega.Equal(tt.httpStatus))
+			g.Expect(body.Detail).NotTo(gomega.BeEmpty())
+			if tt.field != "" {
+				g.Expect(body.Type).To(gomega.Equal(model.ProblemTypeValidation))
+				g.Expect(body.Errors).To(gomega.HaveLen(1))
+				g.Expect(body.Errors[0].Field).To(gomega.Equal(tt.field))
+				g.Expect(body.Errors[0].Code).To(gomega.Equal(tt.code))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Respond with the problem matching a service error. Anything that is not a
+// client error is logged and reported as 500 without its details.
+func errorResponse(ctx *gin.Context, err error) {
+	var (
+		validation   *service.ValidationError
+		notFound     *service.NotFoundError
+		conflict     *service.ConflictError
+		unauthorized *service.UnauthorizedError
+		precondition *service.PreconditionFailedError
+	)
+	switch {
+	case errors.As(err, &validation):
+		p := problem.New(ctx, http.StatusBadRequest, err.Error())
+		p.Type, p.Title, p.Errors = model.ProblemTypeValidation, "Invalid request", fieldErrors(validation.Errors)
+		problem.Write(ctx, p)
+	case errors.As(err, &notFound):
+		problem.Respond(ctx, http.StatusNotFound, err.Error())
+	case errors.As(err, &conflict):
+		p := problem.New(ctx, http.StatusConflict, err.Error())
+		p.Type, p.Title, p.Errors = model.ProblemTypeConflict, "Conflict with the current state", fieldErrors(conflict.Errors)
+		problem.Write(ctx, p)
+	case errors.As(err, &unauthorized):
+		problem.Respond(ctx, http.StatusUnauthorized, err.Error())
+	case errors.As(err, &precondition):
+		problem.Respond(ctx, http.StatusPreconditionFailed, err.Error())
+	default:
+		log.Errorf("Internal error handling %s %s: %s", ctx.Request.Method, ctx.FullPath(), err.Error())
+		problem.Respond(ctx, http.StatusInternalServerError, "")
+	}
+}
+
+func fieldErrors(errs []service.FieldError) []model.FieldError {
+	fields := make([]model.FieldError, len(errs))
+	for i, e := range errs {
+		fields[i] = model.FieldError{Field: e.Field, Code: e.Code, Message: e.Message}
+	}
+	return fields
+}
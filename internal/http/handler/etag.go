@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Strong entity tag of a user version
+func userETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// Strong entity tag of a user list, which changes with any user. ETags are
+// scoped to the URL, so pages and filters can share it.
+func userListETag(marker *model.ChangeMarker) string {
+	return `"list-` + strconv.FormatUint(marker.Version, 10) + `"`
+}
+
+// Send the validators and Cache-Control of a representation. When the
+// request's If-None-Match or If-Modified-Since shows the client already has
+// it, respond 304 Not Modified and return true.
+func notModified(ctx *gin.Context, etag string, lastModified time.Time, cacheControl string) bool {
+	ctx.Header("ETag", etag)
+	ctx.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	if cacheControl != "" {
+		ctx.Header("Cache-Control", cacheControl)
+	}
+
+	// If-Modified-Since is ignored when If-None-Match is sent
+	if ifNoneMatch := ctx.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		if !etagListMatches(ifNoneMatch, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since"))
+		if err != nil || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	ctx.Status(http.StatusNotModified)
+	return true
+}
+
+// Whether a list of entity tags such as If-None-Match matches etag, using the
+// weak comparison
+func etagListMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Version named by the If-Match header, or nil when it is absent or "*". On
+// failure the problem has been written and false is returned.
+func ifMatchVersion(ctx *gin.Context) (*uint, bool) {
+	tag := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if tag == "" || tag == "*" {
+		return nil, true
+	}
+	if strings.Contains(tag, ",") {
+		problem.Respond(ctx, http.StatusBadRequest, "If-Match must name a single entity tag")
+		return nil, false
+	}
+	weak := strings.HasPrefix(tag, "W/")
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		problem.Respond(ctx, http.StatusBadRequest, "If-Match must be a quoted entity tag")
+		return nil, false
+	}
+	// If-Match compares strongly, so weak tags never match
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32)
+	if weak || err != nil || version == 0 {
+		problem.Respond(ctx, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return nil, false
+	}
+	v := uint(version)
+	return &v, true
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService service.HealthService
+}
+
+func NewHealthHandler(service service.HealthService) HealthHandler {
+	return HealthHandler{
+		healthService: service,
+	}
+}
+
+// Reports that the process is alive
+func (h *HealthHandler) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.healthService.Live())
+}
+
+// Reports whether the server and its dependencies can serve requests
+func (h *HealthHandler) Ready(ctx *gin.Context) {
+	health := h.healthService.Ready(ctx.Request.Context())
+	if health.Status != model.HealthOK {
+		ctx.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	ctx.JSON(http.StatusOK, health)
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"atmail/internal/config"
 	"atmail/internal/helper"
+	"atmail/internal/http/middleware"
+	"atmail/internal/http/problem"
 	"atmail/internal/model"
 	"atmail/internal/service"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -14,11 +18,13 @@ const SUCCESS = "Successfully deleted"
 
 type UserHandler struct {
 	userService service.UserService
+	cache       config.UserCacheConfig
 }
 
-func NewUserHandler(service service.UserService) UserHandler {
+func NewUserHandler(service service.UserService, cache config.UserCacheConfig) UserHandler {
 	return UserHandler{
 		userService: service,
+		cache:       cache,
 	}
 }
 
@@ -26,29 +32,40 @@ func NewUserHandler(service service.UserService) UserHandler {
 // @Description Create User
 // @Tags 		Users
 // @Id 			Create
+// @Accept 	json
 // @Produce 	json
 // @Param 		Body  body  model.UserRequest  true  "User Details"
 // @Router 		/users [post]
 // @Success 	201 {object} model.User
-// @Failure      400 {object} model.Error
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      409 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+// @Failure      500 {object} model.Problem
 // @Security 	BasicAuth
+// @Security 	BearerAuth
+// @Security 	ApiKeyAuth
 func (u *UserHandler) Create(ctx *gin.Context) {
 	log.Infoln("Creating user...")
 	var req model.UserRequest
-	ctx.BindJSON(&req)
-	if err := u.userService.ValidateNewUser(req); err != nil {
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if err := u.userService.ValidateNewUser(ctx.Request.Context(), req); err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), req)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 
-	newUser, err := u.userService.Save(req)
+	newUser, err := u.userService.Save(ctx.Request.Context(), req)
 	if err != nil {
 		log.Debugf("Error creating user: %+v %+v", err.Error(), req)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 	log.Infoln("Successfully created user.")
+	ctx.Header("ETag", userETag(newUser.Version))
 	ctx.JSON(http.StatusCreated, newUser)
 }
 
@@ -58,125 +75,344 @@ func (u *UserHandler) Create(ctx *gin.Context) {
 // @Id           Get
 // @Produce      json
 // @Param        id  path  string true "User ID"
+// @Param        If-None-Match      header  string  false  "ETag of the representation the client has"
+// @Param        If-Modified-Since  header  string  false  "Last-Modified of the representation the client has"
 // @Router       /users/{id} [get]
 // @Success      200 {object} model.User
-// @Failure      400 {object} model.Error
-// @Failure      404 {object} model.Error
+// @Header       200 {string} ETag "Version of the user, for If-Match and If-None-Match"
+// @Header       200 {string} Last-Modified "When the user last changed"
+// @Success      304 "The client's copy is current"
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      500 {object} model.Problem
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (u *UserHandler) Get(ctx *gin.Context) {
 	log.Infoln("Retrieving user details...")
 	id, err := helper.CleanID(ctx.Param("id"))
 	if err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	user, statusCode, err := u.userService.Get(*id)
+	user, err := u.userService.Get(ctx.Request.Context(), *id)
 	if err != nil {
 		log.Debugf("Error retrieving user: %+v %+v", err.Error(), id)
-		ctx.JSON(statusCode, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
+		return
+	}
+	if notModified(ctx, userETag(user.Version), user.UpdatedAt, u.cache.Get) {
 		return
 	}
 	log.Infoln("Done retrieving user details.")
-	ctx.JSON(statusCode, user)
+	ctx.JSON(http.StatusOK, user)
 }
 
 // @Summary      Retrieve all users
-// @Description  Retrieve all users
+// @Description  Retrieve a page of users, optionally filtered and sorted
 // @Tags         Users
 // @Id           GetAll
 // @Produce      json
+// @Param        limit            query  int     false  "Page size (1-100, default 20)"
+// @Param        cursor           query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort             query  string  false  "Sort field"  Enums(id, username, email, age)
+// @Param        order            query  string  false  "Sort order"  Enums(asc, desc)
+// @Param        username_prefix  query  string  false  "Only users whose username starts with this value"
+// @Param        email_domain     query  string  false  "Only users whose email belongs to this domain"
+// @Param        min_age          query  int     false  "Minimum age (inclusive)"
+// @Param        max_age          query  int     false  "Maximum age (inclusive)"
+// @Param        created_after    query  string  false  "Only users created after this RFC 3339 time"
+// @Param        updated_before   query  string  false  "Only users last changed before this RFC 3339 time"
+// @Param        include_deleted  query  bool    false  "Include deleted users; requires users:delete"
+// @Param        If-None-Match      header  string  false  "ETag of the representation the client has"
+// @Param        If-Modified-Since  header  string  false  "Last-Modified of the representation the client has"
 // @Router       /users [get]
-// @Success      200 {object} model.User
-// @Failure      400 {object} model.Error
+// @Success      200 {object} model.UserList
+// @Header       200 {string} ETag "Changes whenever any user changes"
+// @Header       200 {string} Last-Modified "When any user last changed"
+// @Success      304 "The client's copy is current"
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      500 {object} model.Problem
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (u *UserHandler) GetAll(ctx *gin.Context) {
 	log.Infoln("Retrieving all users...")
-	users, err := u.userService.GetAll()
+	var query model.UserQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		log.Debugf("Validation failed: %+v", err.Error())
+		problem.Respond(ctx, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+	// Only those who can delete users see the deleted ones
+	if principal := middleware.GetPrincipal(ctx); query.IncludeDeleted && (principal == nil || !principal.HasPermission(model.UsersDelete)) {
+		problem.Respond(ctx, http.StatusForbidden, "insufficient permissions: "+string(model.UsersDelete)+" is required to include deleted users")
+		return
+	}
+
+	// A bad query is an error even when the list has not changed
+	if err := u.userService.ValidateQuery(ctx.Request.Context(), query); err != nil {
+		log.Debugf("Validation failed: %+v", err.Error())
+		errorResponse(ctx, err)
+		return
+	}
+
+	// Read the marker before the list, so a change in between makes the
+	// client fetch again rather than keep a list older than its ETag
+	marker, err := u.userService.ChangeMarker(ctx.Request.Context())
+	if err != nil {
+		errorResponse(ctx, err)
+		return
+	}
+	if notModified(ctx, userListETag(marker), marker.ChangedAt, u.cache.List) {
+		return
+	}
+
+	users, err := u.userService.List(ctx.Request.Context(), query)
 	if err != nil {
 		log.Debugf("Error retrieving user: %+v", err.Error())
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
+	users.Next = nextPageURI(ctx, users.NextCursor)
 	log.Infoln("Done retrieving all users.")
 	ctx.JSON(http.StatusOK, users)
 }
 
+// URI of the page after cursor: the request's own with the cursor replaced.
+// Empty when there is no next page.
+func nextPageURI(ctx *gin.Context, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	next := *ctx.Request.URL
+	values := next.Query()
+	values.Set("cursor", cursor)
+	next.RawQuery = values.Encode()
+	return next.RequestURI()
+}
+
 // @Summary      Update User Dettails
 // @Description  Update User Dettails
 // @Tags         Users
 // @Id           Update
+// @Accept       json
 // @Produce      json
-// @Param        Body  body  model.UserRequest  true  "Update User"
+// @Param        Body  body  model.UserUpdateRequest  true  "Update User"
 // @Param        id  path  string true "User ID"
+// @Param        If-Match  header  string false "ETag of the version being changed"
 // @Router       /users/{id} [put]
 // @Success      200 {object} model.User
-// @Failure      400 {object} model.Error
-// @Failure      404 {object} model.Error
+// @Header       200 {string} ETag "New version of the user"
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      409 {object} model.Problem
+// @Failure      412 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+// @Failure      428 {object} model.Problem
+// @Failure      500 {object} model.Problem
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (u *UserHandler) Update(ctx *gin.Context) {
 	log.Infoln("Updating user details...")
 	id, err := helper.CleanID(ctx.Param("id"))
 	if err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var req model.User
-	ctx.BindJSON(&req)
-	req.ID = *id
-	statusCode, err := u.userService.ValidateExistingUser(req)
-	if err != nil {
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	var req model.UserUpdateRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if err := u.userService.ValidateExistingUser(ctx.Request.Context(), *id, req); err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), req)
-		ctx.JSON(statusCode, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 
-	newUser, err := u.userService.Update(req)
+	newUser, err := u.userService.Update(ctx.Request.Context(), *id, req, version)
 	if err != nil {
 		log.Debugf("Error updating user: %+v %+v", err.Error(), req)
-		ctx.JSON(statusCode, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 	log.Infoln("Successfully updated user details.")
+	ctx.Header("ETag", userETag(newUser.Version))
 	ctx.JSON(http.StatusOK, newUser)
 }
 
+// @Summary      Patch User
+// @Description  Change some fields of a user with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902). The patched user is validated as a whole.
+// @Tags         Users
+// @Id           Patch
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        Body  body  object  true  "Merge patch object or array of patch operations"
+// @Param        id  path  string true "User ID"
+// @Param        If-Match  header  string false "ETag of the version being changed"
+// @Router       /users/{id} [patch]
+// @Success      200 {object} model.User
+// @Header       200 {string} ETag "New version of the user"
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      409 {object} model.Problem
+// @Failure      412 {object} model.Problem
+// @Failure      413 {object} model.Problem
+// @Failure      415 {object} model.Problem
+// @Failure      428 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (u *UserHandler) Patch(ctx *gin.Context) {
+	log.Infoln("Patching user...")
+	id, err := helper.CleanID(ctx.Param("id"))
+	if err != nil {
+		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	patch, ok := bindPatch(ctx)
+	if !ok {
+		return
+	}
+	user, err := u.userService.Patch(ctx.Request.Context(), *id, *patch, version)
+	if err != nil {
+		log.Debugf("Error patching user: %+v %s", err.Error(), patch.Document)
+		errorResponse(ctx, err)
+		return
+	}
+	log.Infoln("Successfully patched user.")
+	ctx.Header("ETag", userETag(user.Version))
+	ctx.JSON(http.StatusOK, user)
+}
+
 // @Title        Delete User
 // @Summary      Delete User
-// @Description  Delete User
+// @Description  Soft delete a user, who can be restored until purged. With purge=true the user, deleted or not, is removed permanently.
 // @Tags         Users
 // @Id           Delete
 // @Produce      json
 // @Param        id  path  string true "User ID"
+// @Param        purge  query  bool false "Remove the user permanently"
+// @Param        If-Match  header  string false "ETag of the version being changed"
 // @Router       /users/{id} [delete]
 // @Success      200 string string
-// @Failure      400 {object} model.Error
-// @Failure      404 {object} model.Error
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      412 {object} model.Problem
+// @Failure      428 {object} model.Problem
+// @Failure      500 {object} model.Problem
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 func (u *UserHandler) Delete(ctx *gin.Context) {
 	log.Infoln("Deleting user...")
 	id, err := helper.CleanID(ctx.Param("id"))
 	if err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	statusCode, err := u.userService.ValidateID(*id)
-	if err != nil {
+	purge := false
+	if value := ctx.Query("purge"); value != "" {
+		if purge, err = strconv.ParseBool(value); err != nil {
+			problem.Respond(ctx, http.StatusBadRequest, "purge must be true or false")
+			return
+		}
+	}
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	if purge {
+		if err := u.userService.Purge(ctx.Request.Context(), *id, version); err != nil {
+			log.Debugf("Error purging user: %+v %+v", err.Error(), id)
+			errorResponse(ctx, err)
+			return
+		}
+		log.Infoln("Successfully purged user...")
+		ctx.JSON(http.StatusOK, SUCCESS)
+		return
+	}
+	if err := u.userService.ValidateID(ctx.Request.Context(), *id); err != nil {
 		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
-		ctx.JSON(statusCode, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 
-	if err := u.userService.Delete(*id); err != nil {
+	if err := u.userService.Delete(ctx.Request.Context(), *id, version); err != nil {
 		log.Debugf("Error deleting user: %+v %+v", err.Error(), id)
-		ctx.JSON(http.StatusBadRequest, model.Error{Error: err.Error()})
+		errorResponse(ctx, err)
 		return
 	}
 	log.Infoln("Successfully deleted user...")
 	ctx.JSON(http.StatusOK, SUCCESS)
 }
+
+// @Title        Restore User
+// @Summary      Restore User
+// @Description  Undo the deletion of a user that has not been purged
+// @Tags         Users
+// @Id           Restore
+// @Produce      json
+// @Param        id  path  string true "User ID"
+// @Param        If-Match  header  string false "ETag of the version being changed"
+// @Router       /users/{id}/restore [post]
+// @Success      200 {object} model.User
+// @Header       200 {string} ETag "Version of the user, for If-Match"
+// @Failure      400 {object} model.Problem
+// @Failure      403 {object} model.Problem
+// @Failure      404 {object} model.Problem
+// @Failure      409 {object} model.Problem
+// @Failure      412 {object} model.Problem
+// @Failure      428 {object} model.Problem
+// @Failure      500 {object} model.Problem
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (u *UserHandler) Restore(ctx *gin.Context) {
+	log.Infoln("Restoring user...")
+	id, err := helper.CleanID(ctx.Param("id"))
+	if err != nil {
+		log.Debugf("Validation failed: %+v %+v", err.Error(), id)
+		problem.Respond(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	user, err := u.userService.Restore(ctx.Request.Context(), *id, version)
+	if err != nil {
+		log.Debugf("Error restoring user: %+v %+v", err.Error(), id)
+		errorResponse(ctx, err)
+		return
+	}
+	log.Infoln("Successfully restored user.")
+	ctx.Header("ETag", userETag(user.Version))
+	ctx.JSON(http.StatusOK, user)
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"atmail/internal/config"
+	"atmail/internal/http/middleware"
 	mock_service "atmail/internal/mock"
 	"atmail/internal/model"
+	"atmail/internal/service"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -10,6 +13,7 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -24,7 +28,8 @@ func TestUserHandler_Get(t *testing.T) {
 		err        error
 	}{
 		{name: "Get user successfully", id: "1", httpStatus: 200, err: nil},
-		{name: "User not found", id: "100", httpStatus: 404, err: errors.New("user not found")},
+		{name: "User not found", id: "100", httpStatus: 404, err: &service.NotFoundError{Resource: "user"}},
+		{name: "Database failure", id: "1", httpStatus: 500, err: &service.InternalError{Err: errors.New("connection refused")}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -32,14 +37,15 @@ func TestUserHandler_Get(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			serviceMock := mock_service.NewMockUserService(ctrl)
-			serviceMock.EXPECT().Get(gomock.Any()).Return(&model.User{
+			serviceMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&model.User{
 				ID:       1,
 				Username: "username1",
 				Email:    "email1",
 				Age:      50,
-			}, tt.httpStatus, tt.err).Times(1)
+				Version:  7,
+			}, tt.err).Times(1)
 
-			handler := NewUserHandler(serviceMock)
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
 			router := gin.New()
 			router.GET("/users/:id", handler.Get)
 
@@ -49,6 +55,134 @@ func TestUserHandler_Get(t *testing.T) {
 			router.ServeHTTP(writer, req)
 
 			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.err == nil {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.Equal(`"7"`))
+			}
+			// Internal failures are not leaked to clients
+			g.Expect(writer.Body.String()).NotTo(gomega.ContainSubstring("connection refused"))
+		})
+	}
+}
+
+func TestUserHandler_GetAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		list       *model.UserList
+		httpStatus int
+		wantNext   string
+		invalid    error
+		err        error
+	}{
+		{name: "Get users successfully", query: "?limit=1&sort=age", httpStatus: 200, list: &model.UserList{
+			Items:      []model.User{{ID: 1, Username: "username1", Email: "email1", Age: 50}},
+			Total:      2,
+			NextCursor: "abc",
+		}, wantNext: "/users?cursor=abc&limit=1&sort=age"},
+		{name: "Invalid sort field", query: "?sort=password", httpStatus: 400, invalid: &service.ValidationError{Errors: []service.FieldError{{Field: "sort", Code: "sort_invalid", Message: "invalid sort field"}}}},
+		{name: "Database failure", query: "", httpStatus: 500, err: &service.InternalError{Err: errors.New("connection refused")}},
+		{name: "Invalid query parameter", query: "?min_age=abc", httpStatus: 400},
+		{name: "Filter by time", query: "?created_after=2024-01-01T00:00:00Z&updated_before=2024-02-01T00:00:00%2B02:00", httpStatus: 200, list: &model.UserList{Items: []model.User{}}},
+		{name: "Invalid time", query: "?created_after=yesterday", httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			if tt.invalid != nil {
+				serviceMock.EXPECT().ValidateQuery(gomock.Any(), gomock.Any()).Return(tt.invalid).Times(1)
+			}
+			if tt.list != nil || tt.err != nil {
+				serviceMock.EXPECT().ValidateQuery(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				serviceMock.EXPECT().ChangeMarker(gomock.Any()).Return(&model.ChangeMarker{Version: 3}, nil).Times(1)
+				serviceMock.EXPECT().List(gomock.Any(), gomock.Any()).Return(tt.list, tt.err).Times(1)
+			}
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.GET("/users", handler.GetAll)
+
+			req, err := http.NewRequest(http.MethodGet, "/users"+tt.query, nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.wantNext != "" {
+				var got model.UserList
+				g.Expect(json.Unmarshal(writer.Body.Bytes(), &got)).To(gomega.Succeed())
+				g.Expect(got.Next).To(gomega.Equal(tt.wantNext))
+			}
+		})
+	}
+}
+
+func TestUserHandler_ConditionalGet(t *testing.T) {
+	changedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		value      string
+		httpStatus int
+	}{
+		{name: "User unchanged since ETag", path: "/users/1", header: "If-None-Match", value: `"7"`, httpStatus: 304},
+		{name: "User changed since ETag", path: "/users/1", header: "If-None-Match", value: `"6"`, httpStatus: 200},
+		{name: "User weak ETag in a list", path: "/users/1", header: "If-None-Match", value: `"6", W/"7"`, httpStatus: 304},
+		{name: "User unchanged since date", path: "/users/1", header: "If-Modified-Since", value: changedAt.Format(http.TimeFormat), httpStatus: 304},
+		{name: "User changed since date", path: "/users/1", header: "If-Modified-Since", value: changedAt.Add(-time.Second).Format(http.TimeFormat), httpStatus: 200},
+		{name: "Users unchanged since ETag", path: "/users", header: "If-None-Match", value: `"list-3"`, httpStatus: 304},
+		{name: "Users changed since ETag", path: "/users", header: "If-None-Match", value: `"list-2"`, httpStatus: 200},
+		{name: "Users match any", path: "/users", header: "If-None-Match", value: "*", httpStatus: 304},
+		{name: "Users unchanged since date", path: "/users", header: "If-Modified-Since", value: changedAt.Format(http.TimeFormat), httpStatus: 304},
+		{name: "Users unchanged but query invalid", path: "/users?sort=password", header: "If-None-Match", value: `"list-3"`, httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			serviceMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&model.User{ID: 1, Version: 7, UpdatedAt: changedAt}, nil).AnyTimes()
+			serviceMock.EXPECT().ChangeMarker(gomock.Any()).Return(&model.ChangeMarker{Version: 3, ChangedAt: changedAt}, nil).AnyTimes()
+			if tt.httpStatus == http.StatusBadRequest {
+				serviceMock.EXPECT().ValidateQuery(gomock.Any(), gomock.Any()).Return(&service.ValidationError{Errors: []service.FieldError{{Field: "sort", Code: "sort_invalid", Message: "invalid sort field"}}}).Times(1)
+			} else {
+				serviceMock.EXPECT().ValidateQuery(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			}
+			if tt.path == "/users" && tt.httpStatus == 200 {
+				serviceMock.EXPECT().List(gomock.Any(), gomock.Any()).Return(&model.UserList{}, nil).Times(1)
+			}
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{Get: "private, no-cache", List: "public, max-age=60"})
+			router := gin.New()
+			router.GET("/users", handler.GetAll)
+			router.GET("/users/:id", handler.Get)
+
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set(tt.header, tt.value)
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.httpStatus == http.StatusBadRequest {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.BeEmpty())
+				return
+			}
+			g.Expect(writer.Header().Get("Last-Modified")).To(gomega.Equal(changedAt.Format(http.TimeFormat)))
+			if tt.path == "/users" {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.Equal(`"list-3"`))
+				g.Expect(writer.Header().Get("Cache-Control")).To(gomega.Equal("public, max-age=60"))
+			} else {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.Equal(`"7"`))
+				g.Expect(writer.Header().Get("Cache-Control")).To(gomega.Equal("private, no-cache"))
+			}
+			if tt.httpStatus == http.StatusNotModified {
+				g.Expect(writer.Body.Len()).To(gomega.BeZero())
+			}
 		})
 	}
 }
@@ -61,10 +195,14 @@ func TestUserHandler_Create(t *testing.T) {
 		age        int
 		httpStatus int
 		err        error
+		saveErr    error
+		field      string
 	}{
 		{name: "Create user successfully", httpStatus: 201, username: "username1", email: "email1@gmail.com", age: 34, err: nil},
-		{name: "Email already exists", httpStatus: 400, username: "username1", email: "email1@gmail.com", age: 34, err: errors.New("email already exists")},
-		{name: "Username already exists", httpStatus: 400, username: "username1", email: "email1@gmail.com", age: 34, err: errors.New("username already exists")},
+		{name: "Invalid email", httpStatus: 400, username: "username1", email: "email1", age: 34, err: &service.ValidationError{Errors: []service.FieldError{{Field: "email", Code: "email_invalid", Message: "invalid email"}}}, field: "email"},
+		{name: "Email already exists", httpStatus: 409, username: "username1", email: "email1@gmail.com", age: 34, err: &service.ConflictError{Errors: []service.FieldError{{Field: "email", Code: "email_exists", Message: "email already exists"}}}, field: "email"},
+		{name: "Username already exists", httpStatus: 409, username: "username1", email: "email1@gmail.com", age: 34, err: &service.ConflictError{Errors: []service.FieldError{{Field: "username", Code: "username_exists", Message: "username already exists"}}}, field: "username"},
+		{name: "Email saved concurrently", httpStatus: 409, username: "username1", email: "email1@gmail.com", age: 34, saveErr: &service.ConflictError{Errors: []service.FieldError{{Field: "email", Code: "email_exists", Message: "email already exists"}}}, field: "email"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -72,36 +210,44 @@ func TestUserHandler_Create(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			serviceMock := mock_service.NewMockUserService(ctrl)
-			serviceMock.EXPECT().ValidateNewUser(gomock.Any()).Return(tt.err).Times(1)
-			if tt.err == nil {
-				serviceMock.EXPECT().Save(gomock.Any()).Return(&model.User{
+			serviceMock.EXPECT().ValidateNewUser(gomock.Any(), gomock.Any()).Return(tt.err).Times(1)
+			if tt.err == nil && tt.saveErr == nil {
+				serviceMock.EXPECT().Save(gomock.Any(), gomock.Any()).Return(&model.User{
 					ID:       1,
 					Username: tt.username,
 					Email:    tt.email,
 					Age:      tt.age,
-				}, tt.err).Times(1)
+				}, nil).Times(1)
+			} else if tt.err == nil {
+				serviceMock.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil, tt.saveErr).Times(1)
 			}
 
-			handler := NewUserHandler(serviceMock)
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
 			router := gin.New()
 			router.POST("/users", handler.Create)
-			var reqBytes []byte
-			if tt.err == nil {
-				r, err := json.Marshal(model.User{
-					Username: tt.username,
-					Email:    tt.email,
-					Age:      tt.age,
-				})
-				g.Expect(err).To(gomega.BeNil())
-				reqBytes = r
-			}
+			reqBytes, err := json.Marshal(model.UserRequest{
+				Username: tt.username,
+				Email:    tt.email,
+				Age:      tt.age,
+			})
+			g.Expect(err).To(gomega.BeNil())
 
 			req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(reqBytes))
 			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/json")
 			writer := httptest.NewRecorder()
 			router.ServeHTTP(writer, req)
 
 			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.field != "" {
+				g.Expect(writer.Header().Get("Content-Type")).To(gomega.Equal("application/problem+json"))
+				var body model.Problem
+				g.Expect(json.Unmarshal(writer.Body.Bytes(), &body)).To(gomega.Succeed())
+				g.Expect(body.Status).To(gomega.Equal(tt.httpStatus))
+				g.Expect(body.Instance).To(gomega.Equal("/users"))
+				g.Expect(body.Errors).To(gomega.HaveLen(1))
+				g.Expect(body.Errors[0].Field).To(gomega.Equal(tt.field))
+			}
 		})
 	}
 }
@@ -117,8 +263,8 @@ func TestUserHandler_Update(t *testing.T) {
 		err        error
 	}{
 		{name: "Update user successfully", httpStatus: 200, id: 1, username: "username1", email: "email1@gmail.com", age: 34, err: nil},
-		{name: "Email already exists", httpStatus: 400, id: 1, username: "username1", email: "email1@gmail.com", age: 34, err: errors.New("email already exists")},
-		{name: "Invalid ID", httpStatus: 404, id: 100, username: "username1", email: "email1@gmail.com", age: 34, err: errors.New("no record found")},
+		{name: "Email already exists", httpStatus: 409, id: 1, username: "username1", email: "email1@gmail.com", age: 34, err: &service.ConflictError{Errors: []service.FieldError{{Field: "email", Code: "email_exists", Message: "email already exists"}}}},
+		{name: "Invalid ID", httpStatus: 404, id: 100, username: "username1", email: "email1@gmail.com", age: 34, err: &service.NotFoundError{Resource: "user"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -126,9 +272,9 @@ func TestUserHandler_Update(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			serviceMock := mock_service.NewMockUserService(ctrl)
-			serviceMock.EXPECT().ValidateExistingUser(gomock.Any()).Return(tt.httpStatus, tt.err).Times(1)
+			serviceMock.EXPECT().ValidateExistingUser(gomock.Any(), tt.id, gomock.Any()).Return(tt.err).Times(1)
 			if tt.err == nil {
-				serviceMock.EXPECT().Update(gomock.Any()).Return(&model.User{
+				serviceMock.EXPECT().Update(gomock.Any(), tt.id, gomock.Any(), nil).Return(&model.User{
 					ID:       1,
 					Username: tt.username,
 					Email:    tt.email,
@@ -136,37 +282,118 @@ func TestUserHandler_Update(t *testing.T) {
 				}, tt.err).Times(1)
 			}
 
-			handler := NewUserHandler(serviceMock)
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
 			router := gin.New()
 			router.PUT("/users/:id", handler.Update)
-			var reqBytes []byte
-			if tt.err == nil {
-				r, err := json.Marshal(model.User{
-					ID:       tt.id,
-					Username: tt.username,
-					Email:    tt.email,
-					Age:      tt.age,
-				})
-				g.Expect(err).To(gomega.BeNil())
-				reqBytes = r
+			reqBytes, err := json.Marshal(model.UserUpdateRequest{
+				Username: tt.username,
+				Email:    tt.email,
+				Age:      tt.age,
+			})
+			g.Expect(err).To(gomega.BeNil())
+
+			req, err := http.NewRequest(http.MethodPut, "/users/"+strconv.Itoa(int(tt.id)), bytes.NewReader(reqBytes))
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/json")
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		httpStatus  int
+		err         error
+	}{
+		{name: "Merge patch", contentType: "application/merge-patch+json", body: `{"age":30}`, httpStatus: 200},
+		{name: "JSON Patch", contentType: "application/json-patch+json", body: `[{"op":"replace","path":"/age","value":30}]`, httpStatus: 200},
+		{name: "Invalid merged user", contentType: "application/merge-patch+json", body: `{"age":null}`, httpStatus: 400, err: &service.ValidationError{Errors: []service.FieldError{{Field: "age", Code: "age_invalid", Message: "invalid age"}}}},
+		{name: "Failed test operation", contentType: "application/json-patch+json", body: `[{"op":"test","path":"/age","value":1}]`, httpStatus: 409, err: &service.ConflictError{Errors: []service.FieldError{{Field: "patch", Code: "patch_test_failed", Message: "test failed"}}}},
+		{name: "User not found", contentType: "application/merge-patch+json", body: `{"age":30}`, httpStatus: 404, err: &service.NotFoundError{Resource: "user"}},
+		{name: "Plain JSON", contentType: "application/json", body: `{"age":30}`, httpStatus: 415},
+		{name: "Empty body", contentType: "application/merge-patch+json", body: ``, httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			if tt.httpStatus != 415 && tt.body != "" {
+				patch := model.UserPatch{MediaType: tt.contentType, Document: []byte(tt.body)}
+				serviceMock.EXPECT().Patch(gomock.Any(), uint(1), patch, nil).Return(&model.User{ID: 1, Username: "username1", Email: "email1@gmail.com", Age: 30}, tt.err).Times(1)
 			}
-			idError := tt.err
-			if idError != nil && idError.Error() == "no record found" {
-				r, err := json.Marshal(model.User{
-					Username: tt.username,
-					Email:    tt.email,
-					Age:      tt.age,
-				})
-				g.Expect(err).To(gomega.BeNil())
-				reqBytes = r
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.PATCH("/users/:id", handler.Patch)
+
+			req, err := http.NewRequest(http.MethodPatch, "/users/1", bytes.NewBufferString(tt.body))
+			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", tt.contentType)
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.httpStatus == 415 {
+				g.Expect(writer.Header().Get("Accept-Patch")).To(gomega.ContainSubstring("application/merge-patch+json"))
+			}
+		})
+	}
+}
+
+func TestUserHandler_IfMatch(t *testing.T) {
+	version := uint(3)
+	tests := []struct {
+		name       string
+		ifMatch    string
+		version    *uint
+		httpStatus int
+		err        error
+	}{
+		{name: "No If-Match", httpStatus: 200},
+		{name: "Any version", ifMatch: "*", httpStatus: 200},
+		{name: "Current version", ifMatch: `"3"`, version: &version, httpStatus: 200},
+		{name: "Stale version", ifMatch: `"3"`, version: &version, httpStatus: 412, err: &service.PreconditionFailedError{Message: "user has changed since it was read"}},
+		{name: "Weak tag never matches", ifMatch: `W/"3"`, httpStatus: 412},
+		{name: "Unknown tag", ifMatch: `"abc"`, httpStatus: 412},
+		{name: "Unquoted tag", ifMatch: `3`, httpStatus: 400},
+		{name: "Several tags", ifMatch: `"3", "4"`, httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			if tt.httpStatus == 200 || tt.err != nil {
+				patch := model.UserPatch{MediaType: "application/merge-patch+json", Document: []byte(`{"age":30}`)}
+				serviceMock.EXPECT().Patch(gomock.Any(), uint(1), patch, tt.version).Return(&model.User{ID: 1, Age: 30, Version: 4}, tt.err).Times(1)
 			}
 
-			req, err := http.NewRequest(http.MethodPut, "/users/"+strconv.Itoa(int(tt.id)), bytes.NewReader(reqBytes))
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.PATCH("/users/:id", handler.Patch)
+
+			req, err := http.NewRequest(http.MethodPatch, "/users/1", bytes.NewBufferString(`{"age":30}`))
 			g.Expect(err).To(gomega.BeNil())
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
 			writer := httptest.NewRecorder()
 			router.ServeHTTP(writer, req)
 
 			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.httpStatus == 200 {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.Equal(`"4"`))
+			}
 		})
 	}
 }
@@ -182,7 +409,7 @@ func TestUserHandler_Delete(t *testing.T) {
 		err        error
 	}{
 		{name: "Delete user successfully", httpStatus: 200, id: 1, err: nil},
-		{name: "Invalid ID", httpStatus: 404, id: 100, err: errors.New("no record found")},
+		{name: "Invalid ID", httpStatus: 404, id: 100, err: &service.NotFoundError{Resource: "user"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -190,12 +417,12 @@ func TestUserHandler_Delete(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			serviceMock := mock_service.NewMockUserService(ctrl)
-			serviceMock.EXPECT().ValidateID(gomock.Any()).Return(tt.httpStatus, tt.err).Times(1)
+			serviceMock.EXPECT().ValidateID(gomock.Any(), gomock.Any()).Return(tt.err).Times(1)
 			if tt.err == nil {
-				serviceMock.EXPECT().Delete(gomock.Any()).Return(tt.err).Times(1)
+				serviceMock.EXPECT().Delete(gomock.Any(), gomock.Any(), nil).Return(tt.err).Times(1)
 			}
 
-			handler := NewUserHandler(serviceMock)
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
 			router := gin.New()
 			router.DELETE("/users/:id", handler.Delete)
 
@@ -208,3 +435,153 @@ func TestUserHandler_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_Purge(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		httpStatus int
+		err        error
+	}{
+		{name: "Purge user successfully", query: "?purge=true", httpStatus: 200},
+		{name: "Purge missing user", query: "?purge=1", httpStatus: 404, err: &service.NotFoundError{Resource: "user"}},
+		{name: "Invalid purge flag", query: "?purge=maybe", httpStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			// Purging skips ValidateID, which does not see deleted users
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			if tt.httpStatus != 400 {
+				serviceMock.EXPECT().Purge(gomock.Any(), uint(1), nil).Return(tt.err).Times(1)
+			}
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.DELETE("/users/:id", handler.Delete)
+
+			req, err := http.NewRequest(http.MethodDelete, "/users/1"+tt.query, nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
+
+func TestUserHandler_Restore(t *testing.T) {
+	version := uint(4)
+	tests := []struct {
+		name       string
+		ifMatch    string
+		version    *uint
+		httpStatus int
+		err        error
+	}{
+		{name: "Restore user successfully", httpStatus: 200},
+		{name: "Restore the expected version", ifMatch: `"4"`, version: &version, httpStatus: 200},
+		{name: "User is not deleted", httpStatus: 409, err: &service.ConflictError{Errors: []service.FieldError{{Field: "deleted_at", Code: "user_not_deleted", Message: "user is not deleted"}}}},
+		{name: "User not found", httpStatus: 404, err: &service.NotFoundError{Resource: "user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			var user *model.User
+			if tt.err == nil {
+				user = &model.User{ID: 1, Username: "username1", Email: "email1@gmail.com", Age: 30, Version: 5}
+			}
+			serviceMock.EXPECT().Restore(gomock.Any(), uint(1), tt.version).Return(user, tt.err).Times(1)
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.POST("/users/:id/restore", handler.Restore)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/1/restore", nil)
+			g.Expect(err).To(gomega.BeNil())
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.err == nil {
+				g.Expect(writer.Header().Get("ETag")).To(gomega.Equal(`"5"`))
+			}
+		})
+	}
+}
+
+func TestUserHandler_GetAllIncludeDeleted(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *model.Principal
+		httpStatus int
+	}{
+		{name: "Admins see deleted users", principal: model.NewOperatorPrincipal(&model.Operator{ID: 1, Role: model.RoleAdmin}), httpStatus: 200},
+		{name: "Helpdesk cannot", principal: model.NewOperatorPrincipal(&model.Operator{ID: 2, Role: model.RoleHelpdesk}), httpStatus: 403},
+		{name: "Unauthenticated callers cannot", httpStatus: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			serviceMock := mock_service.NewMockUserService(ctrl)
+			if tt.httpStatus == 200 {
+				serviceMock.EXPECT().ValidateQuery(gomock.Any(), model.UserQuery{IncludeDeleted: true}).Return(nil).Times(1)
+				serviceMock.EXPECT().ChangeMarker(gomock.Any()).Return(&model.ChangeMarker{Version: 3}, nil).Times(1)
+				serviceMock.EXPECT().List(gomock.Any(), model.UserQuery{IncludeDeleted: true}).Return(&model.UserList{Items: []model.User{}}, nil).Times(1)
+			}
+
+			handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+			router := gin.New()
+			router.GET("/users", func(ctx *gin.Context) {
+				if tt.principal != nil {
+					ctx.Set(middleware.PRINCIPAL, tt.principal)
+				}
+			}, handler.GetAll)
+
+			req, err := http.NewRequest(http.MethodGet, "/users?include_deleted=true", nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
+
+func TestUserHandler_CreateReportsEveryInvalidField(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	serviceMock := mock_service.NewMockUserService(ctrl)
+	serviceMock.EXPECT().ValidateNewUser(gomock.Any(), gomock.Any()).Return(&service.ValidationError{Errors: []service.FieldError{
+		{Field: "email", Code: "email_invalid", Message: "invalid email"},
+		{Field: "age", Code: "age_invalid", Message: "invalid age"},
+	}}).Times(1)
+
+	handler := NewUserHandler(serviceMock, config.UserCacheConfig{})
+	router := gin.New()
+	router.POST("/users", handler.Create)
+	req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader([]byte(`{"username":"username1","email":"email1","age":0}`)))
+	g.Expect(err).To(gomega.BeNil())
+	req.Header.Set("Content-Type", "application/json")
+	writer := httptest.NewRecorder()
+	router.ServeHTTP(writer, req)
+
+	g.Expect(writer.Code).To(gomega.Equal(http.StatusBadRequest))
+	var body model.Problem
+	g.Expect(json.Unmarshal(writer.Body.Bytes(), &body)).To(gomega.Succeed())
+	g.Expect(body.Type).To(gomega.Equal(model.ProblemTypeValidation))
+	g.Expect(body.Errors).To(gomega.Equal([]model.FieldError{
+		{Field: "email", Code: "email_invalid", Message: "invalid email"},
+		{Field: "age", Code: "age_invalid", Message: "invalid age"},
+	}))
+}
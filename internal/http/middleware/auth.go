@@ -1,28 +1,141 @@
 package middleware
 
 import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"atmail/internal/service"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
-const USERNAME string = "admin"
-const PASSWORD string = "admin"
+// Context key of the authenticated *model.Principal
+const PRINCIPAL = "principal"
+
+type AuthMiddleware struct {
+	operatorService service.OperatorService
+	tokenService    service.TokenService
+	apiKeyService   service.ApiKeyService
+}
+
+func NewAuthMiddleware(operatorService service.OperatorService, tokenService service.TokenService, apiKeyService service.ApiKeyService) *AuthMiddleware {
+	return &AuthMiddleware{
+		operatorService: operatorService,
+		tokenService:    tokenService,
+		apiKeyService:   apiKeyService,
+	}
+}
+
+// Picks the authentication method from the X-API-Key header, the
+// Authorization header scheme or, without either, a verified client certificate
+func (a *AuthMiddleware) Authenticate(ctx *gin.Context) {
+	if ctx.GetHeader("X-API-Key") != "" {
+		a.ApiKeyHandler(ctx)
+		return
+	}
+	authorization := ctx.GetHeader("Authorization")
+	if authorization == "" && ctx.Request.TLS != nil && len(ctx.Request.TLS.VerifiedChains) > 0 {
+		a.ClientCertHandler(ctx)
+		return
+	}
+	scheme, _, _ := strings.Cut(authorization, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		a.BearerHandler(ctx)
+	case strings.EqualFold(scheme, "ApiKey"):
+		a.ApiKeyHandler(ctx)
+	default:
+		a.AuthHandler(ctx)
+	}
+}
 
 // All requests will go through this function
-func AuthHandler(ctx *gin.Context) {
+func (a *AuthMiddleware) AuthHandler(ctx *gin.Context) {
 	username, password, ok := ctx.Request.BasicAuth()
 	// Checks if request has basic auth
 	if !ok {
 		err := errors.New("authentication failed")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
 		return
 	}
 	// Checks if username and password are correct
-	if username != USERNAME || password != PASSWORD {
-		err := errors.New("incorrect username or password")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	operator, err := a.operatorService.Authenticate(ctx.Request.Context(), username, password)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidCredentials) {
+			log.Errorf("Error authenticating operator: %s", err.Error())
+		}
+		problem.AbortWith(ctx, http.StatusUnauthorized, service.ErrInvalidCredentials.Error())
+		return
+	}
+	setPrincipal(ctx, model.NewOperatorPrincipal(operator))
+}
+
+// Accepts requests carrying a signed access token
+func (a *AuthMiddleware) BearerHandler(ctx *gin.Context) {
+	scheme, token, ok := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		err := errors.New("authentication failed")
+		problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+	operator, err := a.tokenService.VerifyAccessToken(ctx.Request.Context(), token)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidToken) {
+			log.Errorf("Error verifying access token: %s", err.Error())
+		}
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		problem.AbortWith(ctx, http.StatusUnauthorized, service.ErrInvalidToken.Error())
+		return
+	}
+	setPrincipal(ctx, model.NewOperatorPrincipal(operator))
+}
+
+// Accepts requests carrying an API key in X-API-Key or as
+// "Authorization: ApiKey <key>"
+func (a *AuthMiddleware) ApiKeyHandler(ctx *gin.Context) {
+	key := ctx.GetHeader("X-API-Key")
+	if key == "" {
+		scheme, value, ok := strings.Cut(ctx.GetHeader("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "ApiKey") {
+			key = value
+		}
+	}
+	if key == "" {
+		err := errors.New("authentication failed")
+		problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+	principal, err := a.apiKeyService.Authenticate(ctx.Request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidApiKey) {
+			log.Errorf("Error authenticating API key: %s", err.Error())
+		}
+		problem.AbortWith(ctx, http.StatusUnauthorized, service.ErrInvalidApiKey.Error())
+		return
+	}
+	setPrincipal(ctx, principal)
+}
+
+// Accepts requests whose verified client certificate has the username of an
+// enabled operator as its common name
+func (a *AuthMiddleware) ClientCertHandler(ctx *gin.Context) {
+	if ctx.Request.TLS == nil || len(ctx.Request.TLS.VerifiedChains) == 0 {
+		err := errors.New("authentication failed")
+		problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+	username := ctx.Request.TLS.VerifiedChains[0][0].Subject.CommonName
+	operator, err := a.operatorService.GetByUsername(ctx.Request.Context(), username)
+	if err != nil || operator.Disabled {
+		if err != nil && !errors.Is(err, service.ErrOperatorNotFound) {
+			log.Errorf("Error authenticating client certificate: %s", err.Error())
+		}
+		err := errors.New("client certificate is not mapped to an operator")
+		problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
 		return
 	}
+	setPrincipal(ctx, model.NewOperatorPrincipal(operator))
 }
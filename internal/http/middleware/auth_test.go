@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	mock_service "atmail/internal/mock"
+	"atmail/internal/model"
+	"atmail/internal/service"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+)
+
+func TestAuthMiddleware_ClientCertHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		commonName string
+		operator   *model.Operator
+		err        error
+		httpStatus int
+	}{
+		{name: "Certificate of an operator", commonName: "admin", operator: &model.Operator{ID: 1, Username: "admin", Role: model.RoleAdmin}, httpStatus: 200},
+		{name: "Certificate of a disabled operator", commonName: "former", operator: &model.Operator{ID: 2, Username: "former", Role: model.RoleAdmin, Disabled: true}, httpStatus: 401},
+		{name: "Certificate of an unknown operator", commonName: "nobody", err: service.ErrOperatorNotFound, httpStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			ctrl := gomock.NewController(t)
+
+			operatorMock := mock_service.NewMockOperatorService(ctrl)
+			operatorMock.EXPECT().GetByUsername(gomock.Any(), tt.commonName).Return(tt.operator, tt.err).Times(1)
+
+			auth := NewAuthMiddleware(operatorMock, nil, nil)
+			router := gin.New()
+			router.GET("/users", auth.Authenticate, func(ctx *gin.Context) {
+				g.Expect(GetPrincipal(ctx).Name).To(gomega.Equal(tt.commonName))
+				// Services see the principal through the request context
+				g.Expect(model.PrincipalFromContext(ctx.Request.Context())).To(gomega.Equal(GetPrincipal(ctx)))
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: tt.commonName}},
+			}}}
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rejects requests whose principal lacks the given permission. Must run
+// after one of the authentication handlers.
+func Authorize(permission model.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := GetPrincipal(ctx)
+		if principal == nil {
+			err := errors.New("authentication failed")
+			problem.AbortWith(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if !principal.HasPermission(permission) {
+			err := errors.New("insufficient permissions: " + string(permission) + " is required")
+			problem.AbortWith(ctx, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+}
+
+// Principal set by the authentication handlers, nil when unauthenticated
+func GetPrincipal(ctx *gin.Context) *model.Principal {
+	value, ok := ctx.Get(PRINCIPAL)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*model.Principal)
+	return principal
+}
+
+// Record the authenticated principal for the handlers and, through the
+// request context, for the services
+func setPrincipal(ctx *gin.Context, principal *model.Principal) {
+	ctx.Set(PRINCIPAL, principal)
+	ctx.Request = ctx.Request.WithContext(model.ContextWithPrincipal(ctx.Request.Context(), principal))
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"atmail/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *model.Principal
+		permission model.Permission
+		httpStatus int
+	}{
+		{name: "Admin can delete", principal: model.NewOperatorPrincipal(&model.Operator{ID: 1, Role: model.RoleAdmin}), permission: model.UsersDelete, httpStatus: 200},
+		{name: "Helpdesk can read", principal: model.NewOperatorPrincipal(&model.Operator{ID: 2, Role: model.RoleHelpdesk}), permission: model.UsersRead, httpStatus: 200},
+		{name: "Helpdesk cannot delete", principal: model.NewOperatorPrincipal(&model.Operator{ID: 2, Role: model.RoleHelpdesk}), permission: model.UsersDelete, httpStatus: 403},
+		{name: "Helpdesk cannot create", principal: model.NewOperatorPrincipal(&model.Operator{ID: 2, Role: model.RoleHelpdesk}), permission: model.UsersWrite, httpStatus: 403},
+		{name: "Unknown role has no permissions", principal: model.NewOperatorPrincipal(&model.Operator{ID: 3, Role: "root"}), permission: model.UsersRead, httpStatus: 403},
+		{name: "Unauthenticated", permission: model.UsersRead, httpStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			router := gin.New()
+			router.GET("/users", func(ctx *gin.Context) {
+				if tt.principal != nil {
+					ctx.Set(PRINCIPAL, tt.principal)
+				}
+			}, Authorize(tt.permission), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/users", nil)
+			g.Expect(err).To(gomega.BeNil())
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"atmail/internal/http/problem"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rejects request bodies larger than limit bytes: up front when the
+// Content-Length says so, otherwise when the handler reads past the limit
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > limit {
+			problem.AbortWith(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", limit))
+			return
+		}
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		httpStatus    int
+	}{
+		{name: "Body within the limit", body: "0123456789", contentLength: 10, httpStatus: http.StatusOK},
+		{name: "Declared length over the limit", body: "0123456789a", contentLength: 11, httpStatus: http.StatusRequestEntityTooLarge},
+		{name: "Chunked body over the limit", body: "0123456789a", contentLength: -1, httpStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			router := gin.New()
+			router.Use(BodyLimit(10))
+			router.POST("/echo", func(ctx *gin.Context) {
+				body, err := io.ReadAll(ctx.Request.Body)
+				if err != nil {
+					ctx.Status(http.StatusRequestEntityTooLarge)
+					return
+				}
+				ctx.String(http.StatusOK, string(body))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+			if tt.httpStatus == http.StatusOK {
+				g.Expect(writer.Body.String()).To(gomega.Equal(tt.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"atmail/internal/http/problem"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rejects requests without an If-Match header with 428 Precondition
+// Required, so clients cannot overwrite changes they have not seen. If-Match:
+// * matches whatever version is current and is rejected the same way.
+func RequireIfMatch(ctx *gin.Context) {
+	switch strings.TrimSpace(ctx.GetHeader("If-Match")) {
+	case "", "*":
+		problem.AbortWith(ctx, http.StatusPreconditionRequired, "If-Match is required: send the ETag of the version being changed")
+		return
+	}
+	ctx.Next()
+}
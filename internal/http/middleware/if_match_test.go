@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestRequireIfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		httpStatus int
+	}{
+		{name: "Missing If-Match", httpStatus: http.StatusPreconditionRequired},
+		{name: "With If-Match", ifMatch: `"1"`, httpStatus: http.StatusOK},
+		{name: "Any version", ifMatch: "*", httpStatus: http.StatusPreconditionRequired},
+		{name: "Any version padded", ifMatch: " * ", httpStatus: http.StatusPreconditionRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			router := gin.New()
+			router.PUT("/users/:id", RequireIfMatch, func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			g.Expect(writer.Code).To(gomega.Equal(tt.httpStatus))
+		})
+	}
+}
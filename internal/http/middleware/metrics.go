@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"atmail/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Records the count and latency of every request. Requests are labelled by
+// route template rather than path so that IDs do not create new series.
+func Metrics(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	status := strconv.Itoa(ctx.Writer.Status())
+	metrics.HTTPRequests.WithLabelValues(route, ctx.Request.Method, status).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(route, ctx.Request.Method, status).Observe(time.Since(start).Seconds())
+}
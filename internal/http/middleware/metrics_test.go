@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"atmail/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetrics_LabelsByRouteTemplate(t *testing.T) {
+	g := gomega.NewWithT(t)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Metrics)
+	engine.GET("/users/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	matched := metrics.HTTPRequests.WithLabelValues("/users/:id", http.MethodGet, "200")
+	unmatched := metrics.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")
+	before, beforeUnmatched := counterValue(matched), counterValue(unmatched)
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	g.Expect(counterValue(matched) - before).To(gomega.Equal(2.0))
+	g.Expect(counterValue(unmatched) - beforeUnmatched).To(gomega.Equal(1.0))
+}
+
+func counterValue(counter prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
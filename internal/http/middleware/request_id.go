@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request IDs accepted from clients; anything else is replaced
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Echoes the caller's X-Request-ID, or a new random one, on the response so
+// that errors and logs can be correlated with the request. The ID and the
+// client's IP are passed on in the request context for the audit log.
+func RequestID(ctx *gin.Context) {
+	id := ctx.GetHeader(problem.RequestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = newRequestID()
+	}
+	ctx.Header(problem.RequestIDHeader, id)
+	meta := model.RequestMeta{RequestID: id, SourceIP: ctx.ClientIP()}
+	ctx.Request = ctx.Request.WithContext(model.ContextWithRequestMeta(ctx.Request.Context(), meta))
+	ctx.Next()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"atmail/internal/http/problem"
+	"atmail/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "Generated when missing", incoming: ""},
+		{name: "Caller's ID is kept", incoming: "abc-123", keep: true},
+		{name: "Invalid ID is replaced", incoming: "bad id\n", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			router := gin.New()
+			router.Use(RequestID)
+			var meta model.RequestMeta
+			router.GET("/fail", func(ctx *gin.Context) {
+				meta = model.RequestMetaFromContext(ctx.Request.Context())
+				problem.Respond(ctx, http.StatusTeapot, "")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			writer := httptest.NewRecorder()
+			router.ServeHTTP(writer, req)
+
+			id := writer.Header().Get("X-Request-ID")
+			g.Expect(id).NotTo(gomega.BeEmpty())
+			if tt.keep {
+				g.Expect(id).To(gomega.Equal(tt.incoming))
+			} else {
+				g.Expect(id).NotTo(gomega.Equal(tt.incoming))
+			}
+			// Services see the ID and the client's address
+			g.Expect(meta).To(gomega.Equal(model.RequestMeta{RequestID: id, SourceIP: "192.0.2.1"}))
+			// Problems carry the same ID
+			var body model.Problem
+			g.Expect(json.Unmarshal(writer.Body.Bytes(), &body)).To(gomega.Succeed())
+			g.Expect(body.RequestID).To(gomega.Equal(id))
+		})
+	}
+}
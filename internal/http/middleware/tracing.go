@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"atmail/internal/tracing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Starts a server span for every request, continuing the trace of an incoming
+// traceparent header. Handlers reach the span through ctx.Request.Context().
+func Tracing(ctx *gin.Context) {
+	parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+	route := ctx.FullPath()
+	name := ctx.Request.Method + " " + route
+	if route == "" {
+		name = ctx.Request.Method
+	}
+	spanCtx, span := tracing.Tracer.Start(parent, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(ctx.Request.URL.Path),
+		),
+	)
+	defer span.End()
+
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	ctx.Next()
+
+	status := ctx.Writer.Status()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	if len(ctx.Errors) > 0 {
+		span.RecordError(ctx.Errors.Last())
+	}
+}
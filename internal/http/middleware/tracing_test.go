@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_ContinuesIncomingTrace(t *testing.T) {
+	g := gomega.NewWithT(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Tracing)
+	var handlerSpan trace.SpanContext
+	engine.GET("/users/:id", func(ctx *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(ctx.Request.Context())
+		ctx.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(gomega.HaveLen(1))
+	g.Expect(spans[0].Name()).To(gomega.Equal("GET /users/:id"))
+	g.Expect(spans[0].SpanKind()).To(gomega.Equal(trace.SpanKindServer))
+	g.Expect(spans[0].Parent().SpanID().String()).To(gomega.Equal("00f067aa0ba902b7"))
+	g.Expect(handlerSpan.TraceID().String()).To(gomega.Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+	g.Expect(handlerSpan.SpanID()).To(gomega.Equal(spans[0].SpanContext().SpanID()))
+}
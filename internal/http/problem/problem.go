@@ -0,0 +1,48 @@
+package problem
+
+import (
+	"atmail/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Header carrying the request ID, set on the response by middleware.RequestID
+const RequestIDHeader = "X-Request-ID"
+
+// Problem with the given status about the current request
+func New(ctx *gin.Context, status int, detail string) model.Problem {
+	return model.Problem{
+		Type:      model.ProblemTypeDefault,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  ctx.Request.URL.Path,
+		RequestID: ctx.Writer.Header().Get(RequestIDHeader),
+	}
+}
+
+// Respond with p
+func Write(ctx *gin.Context, p model.Problem) {
+	ctx.Header("Content-Type", ContentType)
+	ctx.JSON(p.Status, p)
+}
+
+// Respond with p and skip the remaining handlers
+func Abort(ctx *gin.Context, p model.Problem) {
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// Respond with a problem with the given status
+func Respond(ctx *gin.Context, status int, detail string) {
+	Write(ctx, New(ctx, status, detail))
+}
+
+// Respond with a problem with the given status and skip the remaining
+// handlers
+func AbortWith(ctx *gin.Context, status int, detail string) {
+	Abort(ctx, New(ctx, status, detail))
+}
@@ -0,0 +1,28 @@
+package route
+
+import (
+	"atmail/internal/http/handler"
+	"atmail/internal/http/middleware"
+	"atmail/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyRoute struct {
+	handler handler.ApiKeyHandler
+	auth    *middleware.AuthMiddleware
+}
+
+func NewApiKeyRoute(apiKeyHandler handler.ApiKeyHandler, auth *middleware.AuthMiddleware) *ApiKeyRoute {
+	return &ApiKeyRoute{
+		handler: apiKeyHandler,
+		auth:    auth,
+	}
+}
+
+func (a *ApiKeyRoute) Setup(router *gin.RouterGroup) {
+	keys := router.Group("api-keys", a.auth.Authenticate, middleware.Authorize(model.ApiKeysManage))
+	keys.GET("", a.handler.GetAll)
+	keys.POST("", a.handler.Create)
+	keys.DELETE(":id", a.handler.Revoke)
+}
@@ -0,0 +1,27 @@
+package route
+
+import (
+	"atmail/internal/http/handler"
+	"atmail/internal/http/middleware"
+	"atmail/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRoute struct {
+	handler handler.AuditHandler
+	auth    *middleware.AuthMiddleware
+}
+
+func NewAuditRoute(auditHandler handler.AuditHandler, auth *middleware.AuthMiddleware) *AuditRoute {
+	return &AuditRoute{
+		handler: auditHandler,
+		auth:    auth,
+	}
+}
+
+func (a *AuditRoute) Setup(router *gin.RouterGroup) {
+	authorize := []gin.HandlerFunc{a.auth.Authenticate, middleware.Authorize(model.AuditRead)}
+	router.GET("audit", append(authorize, a.handler.List)...)
+	router.GET("users/:id/history", append(authorize, a.handler.History)...)
+}
@@ -0,0 +1,23 @@
+package route
+
+import (
+	"atmail/internal/http/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthRoute struct {
+	handler handler.AuthHandler
+}
+
+func NewAuthRoute(authHandler handler.AuthHandler) *AuthRoute {
+	return &AuthRoute{
+		handler: authHandler,
+	}
+}
+
+func (a *AuthRoute) Setup(router *gin.RouterGroup) {
+	router.POST("auth/login", a.handler.Login)
+	router.POST("auth/refresh", a.handler.Refresh)
+	router.POST("auth/logout", a.handler.Logout)
+}
@@ -0,0 +1,22 @@
+package route
+
+import (
+	"atmail/internal/http/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthRoute struct {
+	handler handler.HealthHandler
+}
+
+func NewHealthRoute(healthHandler handler.HealthHandler) *HealthRoute {
+	return &HealthRoute{
+		handler: healthHandler,
+	}
+}
+
+func (h *HealthRoute) Setup(router *gin.RouterGroup) {
+	router.GET("healthz", h.handler.Live)
+	router.GET("readyz", h.handler.Ready)
+}
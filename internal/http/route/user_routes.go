@@ -1,27 +1,45 @@
 package route
 
 import (
+	"atmail/internal/config"
 	"atmail/internal/http/handler"
 	"atmail/internal/http/middleware"
+	"atmail/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserRoute struct {
 	handler handler.UserHandler
+	auth    *middleware.AuthMiddleware
+	// Whether PUT, PATCH and DELETE must send If-Match
+	requireIfMatch bool
 }
 
-func NewUserRoute(userHandler handler.UserHandler) *UserRoute {
+func NewUserRoute(userHandler handler.UserHandler, auth *middleware.AuthMiddleware, concurrency config.UserConcurrencyConfig) *UserRoute {
 	return &UserRoute{
-		handler: userHandler,
+		handler:        userHandler,
+		auth:           auth,
+		requireIfMatch: concurrency.RequireIfMatch,
 	}
 }
 
 func (u *UserRoute) Setup(router *gin.RouterGroup) {
-	router.Use(middleware.AuthHandler)
-	router.GET("users", u.handler.GetAll)
-	router.GET("users/:id", u.handler.Get)
-	router.POST("users", u.handler.Create)
-	router.PUT("users/:id", u.handler.Update)
-	router.DELETE("users/:id", u.handler.Delete)
+	users := router.Group("users", u.auth.Authenticate)
+	users.GET("", middleware.Authorize(model.UsersRead), u.handler.GetAll)
+	users.GET(":id", middleware.Authorize(model.UsersRead), u.handler.Get)
+	users.POST("", middleware.Authorize(model.UsersWrite), u.handler.Create)
+	users.PUT(":id", u.changes(model.UsersWrite, u.handler.Update)...)
+	users.PATCH(":id", u.changes(model.UsersWrite, u.handler.Patch)...)
+	users.DELETE(":id", u.changes(model.UsersDelete, u.handler.Delete)...)
+	users.POST(":id/restore", u.changes(model.UsersDelete, u.handler.Restore)...)
+}
+
+// Handlers of a route changing an existing user
+func (u *UserRoute) changes(permission model.Permission, handler gin.HandlerFunc) []gin.HandlerFunc {
+	handlers := []gin.HandlerFunc{middleware.Authorize(permission)}
+	if u.requireIfMatch {
+		handlers = append(handlers, middleware.RequireIfMatch)
+	}
+	return append(handlers, handler)
 }
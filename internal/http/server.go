@@ -3,44 +3,164 @@ package http
 import (
 	"atmail/docs"
 	"atmail/internal/config"
+	"atmail/internal/http/middleware"
+	"atmail/internal/http/problem"
 	"atmail/internal/http/route"
+	"atmail/internal/service"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type ServerHTTP struct {
-	engine *gin.Engine
+	engine          *gin.Engine
+	server          *http.Server
+	shutdownTimeout time.Duration
+	tls             config.TLSConfig
+	healthService   service.HealthService
 }
 
-func NewServerHTTP(userRoute *route.UserRoute) *ServerHTTP {
+func NewServerHTTP(userRoute *route.UserRoute, authRoute *route.AuthRoute, apiKeyRoute *route.ApiKeyRoute, auditRoute *route.AuditRoute, healthRoute *route.HealthRoute, healthService service.HealthService) (*ServerHTTP, error) {
 	docs.SwaggerInfo.BasePath = config.GetEnvVariable("SWAGGER_HOST", "/atmail")
+	cfg := config.Server()
 
-	engine := gin.Default()
-	engine.Use(cors.New(cors.Config{
-		AllowOrigins:  []string{"*"},
-		AllowMethods:  []string{"*"},
-		AllowHeaders:  []string{"*"},
-		AllowWildcard: true,
-	}))
+	engine, err := newEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	engine.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"data": "Hello world..."})
 	})
+	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	healthRoute.Setup(&engine.RouterGroup)
 
 	api := engine.Group("/atmail")
 	{
 		api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		authRoute.Setup(api)
 		userRoute.Setup(api)
+		apiKeyRoute.Setup(api)
+		auditRoute.Setup(api)
 	}
 
-	return &ServerHTTP{engine: engine}
+	return &ServerHTTP{
+		engine: engine,
+		server: &http.Server{
+			Addr:              cfg.Address,
+			Handler:           engine,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+		tls:             cfg.TLS,
+		healthService:   healthService,
+	}, nil
 }
 
-func (sh *ServerHTTP) Start() {
-	sh.engine.Run(":80")
+// Engine with the middleware every route shares. The client IP, which the
+// audit log records, only comes from forwarding headers sent by cfg's
+// trusted proxies.
+func newEngine(cfg config.ServerConfig) (*gin.Engine, error) {
+	engine := gin.Default()
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("SERVER_TRUSTED_PROXIES: %w", err)
+	}
+	engine.Use(cors.New(cors.Config{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"*"},
+		AllowHeaders:  []string{"*"},
+		ExposeHeaders: []string{problem.RequestIDHeader, "ETag"},
+		AllowWildcard: true,
+	}))
+	engine.Use(middleware.RequestID, middleware.Tracing, middleware.Metrics, middleware.BodyLimit(cfg.MaxBodyBytes))
+	engine.NoRoute(func(ctx *gin.Context) {
+		problem.Respond(ctx, http.StatusNotFound, "no route matches "+ctx.Request.URL.Path)
+	})
+	return engine, nil
+}
+
+// Serve until SIGINT or SIGTERM, then stop accepting connections and wait
+// for in-flight requests to finish
+func (sh *ServerHTTP) Start() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return sh.Run(ctx)
+}
+
+// Serve until ctx is done, then shut down gracefully
+func (sh *ServerHTTP) Run(ctx context.Context) error {
+	servers := []*http.Server{sh.server}
+	if sh.tls.Enabled() {
+		tlsConfig, err := newTLSConfig(sh.tls)
+		if err != nil {
+			return err
+		}
+		sh.server.TLSConfig = tlsConfig
+		if sh.tls.RedirectAddress != "" {
+			servers = append(servers, &http.Server{
+				Addr:              sh.tls.RedirectAddress,
+				Handler:           redirectHandler(sh.server.Addr),
+				ReadHeaderTimeout: sh.server.ReadHeaderTimeout,
+			})
+		}
+	}
+
+	serveErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			if server.TLSConfig != nil {
+				log.Infof("Listening for HTTPS on %s", server.Addr)
+				serveErr <- server.ListenAndServeTLS("", "")
+				return
+			}
+			log.Infof("Listening on %s", server.Addr)
+			serveErr <- server.ListenAndServe()
+		}(server)
+	}
+
+	var runErr error
+	pending := len(servers)
+	select {
+	case runErr = <-serveErr:
+		pending--
+	case <-ctx.Done():
+	}
+
+	if sh.healthService != nil {
+		sh.healthService.SetShuttingDown()
+	}
+	log.Infof("Shutting down, waiting up to %s for in-flight requests...", sh.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), sh.shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	for ; pending > 0; pending-- {
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) && runErr == nil {
+			runErr = err
+		}
+	}
+	if runErr == nil {
+		log.Infoln("Server stopped.")
+	}
+	return runErr
 }
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/model"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestServerHTTP_RunDrainsInFlightRequests(t *testing.T) {
+	g := gomega.NewWithT(t)
+	started := make(chan struct{})
+	engine := gin.New()
+	engine.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+
+	addr := freeAddress(t)
+	sh := &ServerHTTP{
+		engine:          engine,
+		server:          &http.Server{Addr: addr, Handler: engine},
+		shutdownTimeout: 5 * time.Second,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- sh.Run(ctx) }()
+
+	g.Eventually(func() error {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}).Should(gomega.Succeed())
+
+	respStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			respStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		respStatus <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+	g.Eventually(respStatus, 5*time.Second).Should(gomega.Receive(gomega.Equal(http.StatusOK)))
+	g.Eventually(runErr, 5*time.Second).Should(gomega.Receive(gomega.BeNil()))
+}
+
+func TestServerHTTP_RunReturnsListenErrors(t *testing.T) {
+	g := gomega.NewWithT(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	defer listener.Close()
+
+	sh := &ServerHTTP{
+		engine:          gin.New(),
+		server:          &http.Server{Addr: listener.Addr().String()},
+		shutdownTimeout: time.Second,
+	}
+	g.Expect(sh.Run(context.Background())).NotTo(gomega.Succeed())
+}
+
+func TestNewEngine_SourceIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{name: "Forged X-Forwarded-For is ignored by default", want: "192.0.2.1"},
+		{name: "Trusted proxy forwards the client IP", proxies: []string{"192.0.2.0/24"}, want: "203.0.113.9"},
+		{name: "Other proxies are not believed", proxies: []string{"198.51.100.1"}, want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			engine, err := newEngine(config.ServerConfig{TrustedProxies: tt.proxies, MaxBodyBytes: 1 << 10})
+			g.Expect(err).To(gomega.BeNil())
+			var meta model.RequestMeta
+			engine.GET("/ip", func(ctx *gin.Context) {
+				meta = model.RequestMetaFromContext(ctx.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			req.Header.Set("X-Real-IP", "203.0.113.9")
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+			g.Expect(meta.SourceIP).To(gomega.Equal(tt.want))
+		})
+	}
+
+	_, err := newEngine(config.ServerConfig{TrustedProxies: []string{"not an address"}})
+	gomega.NewWithT(t).Expect(err).NotTo(gomega.BeNil())
+}
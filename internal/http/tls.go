@@ -0,0 +1,139 @@
+package http
+
+import (
+	"atmail/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Serves a key pair from disk and picks up replaced files without a restart.
+// The files are checked at most once per interval, during a TLS handshake.
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	c.checkedAt = time.Now()
+	return c, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.reloadIfChanged()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certReloader) reloadIfChanged() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checkedAt) < c.interval {
+		return
+	}
+	c.checkedAt = time.Now()
+
+	modTime, err := c.latestModTime()
+	if err != nil || !modTime.After(c.modTime) {
+		return
+	}
+	// Keep serving the previous pair if the new one is incomplete or invalid
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		log.Warnf("Error reloading TLS certificate: %s", err.Error())
+		return
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	log.Infoln("Reloaded TLS certificate.")
+}
+
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile, cfg.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch cfg.ClientAuth {
+	case "", "none":
+		return tlsConfig, nil
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid TLS_CLIENT_AUTH %q", cfg.ClientAuth)
+	}
+
+	if cfg.ClientCAFile == "" {
+		return nil, errors.New("TLS_CLIENT_CA_FILE is required when client certificates are enabled")
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in TLS_CLIENT_CA_FILE")
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// Redirects plain HTTP requests to the HTTPS listener at httpsAddress
+func redirectHandler(httpsAddress string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddress)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
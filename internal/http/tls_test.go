@@ -0,0 +1,198 @@
+package http
+
+import (
+	"atmail/internal/config"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, c.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestServerHTTP_RunWithMutualTLS(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+	ca := newTestCert(t, "test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "localhost", ca, false).write(t, dir, "server")
+	client := newTestCert(t, "admin", ca, false)
+
+	engine := gin.New()
+	engine.GET("/whoami", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Request.TLS.VerifiedChains[0][0].Subject.CommonName)
+	})
+	addr := freeAddress(t)
+	sh := &ServerHTTP{
+		engine:          engine,
+		server:          &http.Server{Addr: addr, Handler: engine},
+		shutdownTimeout: time.Second,
+		tls: config.TLSConfig{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+			ClientAuth:   "require",
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sh.Run(ctx)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{client.tlsCertificate(t)},
+	}}}
+	var resp *http.Response
+	g.Eventually(func() error {
+		var err error
+		resp, err = withCert.Get("https://" + addr + "/whoami")
+		return err
+	}).Should(gomega.Succeed())
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+	resp.Body.Close()
+
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err := withoutCert.Get("https://" + addr + "/whoami")
+	g.Expect(err).NotTo(gomega.BeNil())
+
+	// Plain HTTP is answered with an error instead of being served
+	resp, err = http.Get("http://" + addr + "/whoami")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusBadRequest))
+	resp.Body.Close()
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+	ca := newTestCert(t, "test CA", nil, true)
+	certFile, keyFile := newTestCert(t, "first", ca, false).write(t, dir, "server")
+
+	reloader, err := newCertReloader(certFile, keyFile, 0)
+	g.Expect(err).To(gomega.BeNil())
+	cert, _ := reloader.GetCertificate(nil)
+	first, _ := x509.ParseCertificate(cert.Certificate[0])
+	g.Expect(first.Subject.CommonName).To(gomega.Equal("first"))
+
+	newTestCert(t, "second", ca, false).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	g.Expect(os.Chtimes(certFile, later, later)).To(gomega.Succeed())
+	cert, _ = reloader.GetCertificate(nil)
+	second, _ := x509.ParseCertificate(cert.Certificate[0])
+	g.Expect(second.Subject.CommonName).To(gomega.Equal("second"))
+
+	// An unreadable pair keeps the last good certificate
+	g.Expect(os.WriteFile(certFile, []byte("garbage"), 0o600)).To(gomega.Succeed())
+	evenLater := later.Add(time.Minute)
+	g.Expect(os.Chtimes(certFile, evenLater, evenLater)).To(gomega.Succeed())
+	cert, _ = reloader.GetCertificate(nil)
+	g.Expect(cert.Certificate[0]).To(gomega.Equal(second.Raw))
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpsAddress string
+		host         string
+		want         string
+	}{
+		{name: "Default HTTPS port", httpsAddress: ":443", host: "example.com", want: "https://example.com/atmail/users?limit=1"},
+		{name: "Custom HTTPS port", httpsAddress: ":8443", host: "example.com:8080", want: "https://example.com:8443/atmail/users?limit=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			req := httptest.NewRequest(http.MethodGet, "http://"+tt.host+"/atmail/users?limit=1", nil)
+			writer := httptest.NewRecorder()
+			redirectHandler(tt.httpsAddress).ServeHTTP(writer, req)
+			g.Expect(writer.Code).To(gomega.Equal(http.StatusPermanentRedirect))
+			g.Expect(writer.Header().Get("Location")).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestNewTLSConfig_Errors(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+	certFile, keyFile := newTestCert(t, "localhost", nil, false).write(t, dir, "server")
+
+	_, err := newTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "require"})
+	g.Expect(err).NotTo(gomega.BeNil())
+	_, err = newTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "sometimes"})
+	g.Expect(err).NotTo(gomega.BeNil())
+	_, err = newTLSConfig(config.TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile})
+	g.Expect(err).NotTo(gomega.BeNil())
+}
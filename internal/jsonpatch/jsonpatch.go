@@ -0,0 +1,360 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// The patch document itself is malformed
+	ErrInvalidPatch = errors.New("invalid patch")
+	// An operation refers to a location that does not exist
+	ErrPathNotFound = errors.New("path not found")
+	// A test operation did not match the document
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Apply an RFC 7396 JSON Merge Patch to doc
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
+
+type operation struct {
+	op    string
+	path  []string
+	from  []string
+	value interface{}
+	raw   string
+}
+
+// Apply an RFC 6902 JSON Patch to doc. Either every operation is applied or,
+// when one fails, none is.
+func Apply(doc, patch []byte) ([]byte, error) {
+	ops, err := decodeOperations(patch)
+	if err != nil {
+		return nil, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.op, op.raw, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func decodeOperations(patch []byte) ([]operation, error) {
+	var raws []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &raws); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch must be an array of operations", ErrInvalidPatch)
+	}
+	ops := make([]operation, len(raws))
+	for i, raw := range raws {
+		op, err := decodeOperation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d %s", ErrInvalidPatch, i, err.Error())
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func decodeOperation(raw map[string]json.RawMessage) (operation, error) {
+	var op operation
+	if err := decodeMember(raw, "op", &op.op); err != nil {
+		return op, err
+	}
+	if err := decodeMember(raw, "path", &op.raw); err != nil {
+		return op, err
+	}
+	var err error
+	if op.path, err = parsePointer(op.raw); err != nil {
+		return op, err
+	}
+
+	switch op.op {
+	case "add", "replace", "test":
+		if err := decodeMember(raw, "value", &op.value); err != nil {
+			return op, err
+		}
+	case "move", "copy":
+		var from string
+		if err := decodeMember(raw, "from", &from); err != nil {
+			return op, err
+		}
+		if op.from, err = parsePointer(from); err != nil {
+			return op, err
+		}
+		if op.op == "move" && isProperPrefix(op.from, op.path) {
+			return op, errors.New("cannot move a value into one of its children")
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("has unknown op %q", op.op)
+	}
+	return op, nil
+}
+
+func decodeMember(raw map[string]json.RawMessage, name string, dst interface{}) error {
+	value, ok := raw[name]
+	if !ok {
+		return fmt.Errorf("is missing %q", name)
+	}
+	if err := json.Unmarshal(value, dst); err != nil {
+		return fmt.Errorf("has an invalid %q", name)
+	}
+	return nil
+}
+
+// Reference tokens of an RFC 6901 JSON Pointer. The empty pointer refers to
+// the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("has pointer %q that does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return nil, fmt.Errorf("has pointer %q with an invalid escape", pointer)
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isProperPrefix(prefix, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	switch op.op {
+	case "add":
+		return add(doc, op.path, op.value)
+	case "remove":
+		doc, _, err := remove(doc, op.path)
+		return doc, err
+	case "replace":
+		doc, _, err := remove(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, op.value)
+	case "move":
+		doc, value, err := remove(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, value)
+	case "copy":
+		value, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, clone(value))
+	default:
+		value, err := get(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return node, nil
+}
+
+// The node with value added at path. Arrays are returned anew because
+// inserting may reallocate them.
+func add(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			i := len(n)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		if n[i], err = add(n[i], rest, value); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// The node with the value at path removed, and the removed value
+func remove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, node, nil
+	}
+	token, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		if len(rest) == 0 {
+			delete(n, token)
+			return n, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = child
+		return n, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(n[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = child
+		return n, removed, nil
+	default:
+		return nil, nil, ErrPathNotFound
+	}
+}
+
+// Array index of token, which must be a plain decimal no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPathNotFound
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, ErrPathNotFound
+	}
+	return i, nil
+}
+
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			c[key] = clone(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = clone(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,81 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "Replace a member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "Add a member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "Remove a member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "Arrays are replaced", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "Nested objects are merged", doc: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":"g"}}`, want: `{"a":{"b":"c","f":"g"}}`},
+		{name: "Nulls are dropped from new objects", doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "Non-object patch replaces the document", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(got).To(gomega.MatchJSON(tt.want))
+		})
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	gomega.NewWithT(t).Expect(errors.Is(err, ErrInvalidPatch)).To(gomega.BeTrue())
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{name: "Add a member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"foo":"bar","baz":"qux"}`},
+		{name: "Insert into an array", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{name: "Append to an array", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":"qux"}]`, want: `{"foo":["bar","qux"]}`},
+		{name: "Remove a member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		{name: "Remove an array element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "Replace a member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{name: "Move a member", doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, want: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{name: "Move an array element", doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		{name: "Copy a member", doc: `{"foo":{"bar":1}}`, patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, want: `{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{name: "Add a null value", doc: `{}`, patch: `[{"op":"add","path":"/foo","value":null}]`, want: `{"foo":null}`},
+		{name: "Escaped pointer", doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, want: `{"a/b":3}`},
+		{name: "Test passes", doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, want: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{name: "Test fails", doc: `{"baz":"qux"}`, patch: `[{"op":"test","path":"/baz","value":"bar"}]`, wantErr: ErrTestFailed},
+		{name: "Failed operation undoes earlier ones", doc: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2},{"op":"remove","path":"/c"}]`, wantErr: ErrPathNotFound},
+		{name: "Add to a missing parent", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, wantErr: ErrPathNotFound},
+		{name: "Array index out of bounds", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/2","value":"qux"}]`, wantErr: ErrPathNotFound},
+		{name: "Leading zero index", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"remove","path":"/foo/01"}]`, wantErr: ErrPathNotFound},
+		{name: "Move into a child", doc: `{"foo":{"bar":1}}`, patch: `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, wantErr: ErrInvalidPatch},
+		{name: "Unknown op", doc: `{}`, patch: `[{"op":"merge","path":"/foo"}]`, wantErr: ErrInvalidPatch},
+		{name: "Missing value", doc: `{}`, patch: `[{"op":"add","path":"/foo"}]`, wantErr: ErrInvalidPatch},
+		{name: "Invalid pointer", doc: `{}`, patch: `[{"op":"remove","path":"foo"}]`, wantErr: ErrInvalidPatch},
+		{name: "Not an array", doc: `{}`, patch: `{"op":"remove","path":"/foo"}`, wantErr: ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				g.Expect(errors.Is(err, tt.wantErr)).To(gomega.BeTrue(), "error = %v", err)
+				return
+			}
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(got).To(gomega.MatchJSON(tt.want))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "atmail"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	ValidationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_validation_failures_total",
+		Help:      "Rejected user requests by validation failure reason.",
+	}, []string{"reason"})
+)
+
+var (
+	dbCollectorMu sync.Mutex
+	dbCollector   prometheus.Collector
+)
+
+// Expose the connection pool statistics of db, replacing any pool
+// registered before
+func RegisterDB(db *sql.DB, name string) {
+	dbCollectorMu.Lock()
+	defer dbCollectorMu.Unlock()
+	if dbCollector != nil {
+		prometheus.Unregister(dbCollector)
+	}
+	dbCollector = collectors.NewDBStatsCollector(db, name)
+	prometheus.MustRegister(dbCollector)
+}
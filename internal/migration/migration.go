@@ -0,0 +1,305 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Migration files for each driver, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Versions must be unique and never reused.
+//
+//go:embed sql
+var files embed.FS
+
+// Named lock held while migrating, so instances starting together do not
+// race each other
+const lockName = "atmail_schema_migrations"
+
+// Key of the PostgreSQL advisory lock, which takes a number instead of a name
+const postgresLockKey = 7261736869
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// A migration and when it was applied, if it has been
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Row of the schema_migrations tracking table
+type schemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// Migrator for the embedded migrations of db's driver
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Embedded migrations of driver, ordered by version
+func Load(driver string) ([]Migration, error) {
+	dir := path.Join("sql", driver)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		name, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		number, name, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(number, 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration file %s has no version", entry.Name())
+		}
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: name}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, name, version)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Version of the newest embedded migration
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Every embedded migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Apply every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Roll back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.rollback(db, m.migrations[i])
+			}
+		}
+		log.Infoln("No migrations to roll back")
+		return nil
+	})
+}
+
+// Apply or roll back migrations until exactly those up to version are
+// applied. Version 0 rolls back everything.
+func (m *Migrator) To(ctx context.Context, version uint) error {
+	if version != 0 && !m.exists(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for v := range applied {
+			if !m.exists(v) {
+				return fmt.Errorf("database has migration %d applied, which this build does not know", v)
+			}
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.rollback(db, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(db, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) exists(version uint) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Run a migration's up script and record it in one transaction. That makes
+// it atomic on PostgreSQL and SQLite only: MySQL commits implicitly before
+// and after each DDL statement, so a failure part way leaves the earlier
+// statements applied and the migration unrecorded. MySQL migrations therefore
+// put their DDL in a single statement, or are written so that rerunning them
+// after a partial failure succeeds.
+func (m *Migrator) apply(db *gorm.DB, migration Migration) error {
+	log.Infof("Applying migration %d_%s", migration.Version, migration.Name)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := execScript(tx, migration.Up); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+	})
+}
+
+// Run a migration's down script and forget it, in one transaction with the
+// same caveat for MySQL as apply
+func (m *Migrator) rollback(db *gorm.DB, migration Migration) error {
+	log.Infof("Rolling back migration %d_%s", migration.Version, migration.Name)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := execScript(tx, migration.Down); err != nil {
+			return fmt.Errorf("rolling back %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+	})
+}
+
+// Applied migrations keyed by version, creating the tracking table if needed
+func (m *Migrator) applied(db *gorm.DB) (map[uint]schemaMigration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Run fn on a single connection holding the migration lock. SQLite needs
+// none because it allows only one writer at a time.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(db *gorm.DB) error {
+		unlock, err := lock(db)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				log.Errorf("Error releasing migration lock: %s", err.Error())
+			}
+		}()
+		return fn(db)
+	})
+}
+
+func lock(db *gorm.DB) (func() error, error) {
+	switch db.Dialector.Name() {
+	case "mysql":
+		var acquired int
+		if err := db.Raw("SELECT GET_LOCK(?, 60)", lockName).Scan(&acquired).Error; err != nil {
+			return nil, err
+		}
+		if acquired != 1 {
+			return nil, errors.New("timed out waiting for another migration to finish")
+		}
+		return func() error { return db.Exec("SELECT RELEASE_LOCK(?)", lockName).Error }, nil
+	case "postgres":
+		if err := db.Exec("SELECT pg_advisory_lock(?)", postgresLockKey).Error; err != nil {
+			return nil, err
+		}
+		return func() error { return db.Exec("SELECT pg_advisory_unlock(?)", postgresLockKey).Error }, nil
+	default:
+		return func() error { return nil }, nil
+	}
+}
+
+// Run each statement of a script. Statements end with a semicolon at the end
+// of a line, because drivers do not all accept several in one call.
+func execScript(db *gorm.DB, script string) error {
+	for _, statement := range strings.Split(script, ";\n") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
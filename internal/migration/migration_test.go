@@ -0,0 +1,167 @@
+package migration_test
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/migration"
+	"atmail/internal/repository"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+func newSQLiteMigrator(t *testing.T) (*migration.Migrator, *gorm.DB) {
+	db, cleanup, err := repository.NewDB(config.DBConfig{Driver: "sqlite", DSN: config.SQLiteDSN(filepath.Join(t.TempDir(), "atmail.db")), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator, db
+}
+
+func appliedVersions(t *testing.T, migrator *migration.Migrator) []uint {
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []uint{}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func TestLoad(t *testing.T) {
+	g := gomega.NewWithT(t)
+	var versions [][]uint
+	for _, driver := range []string{"mysql", "postgres", "sqlite"} {
+		migrations, err := migration.Load(driver)
+		g.Expect(err).To(gomega.BeNil())
+		driverVersions := []uint{}
+		for _, m := range migrations {
+			g.Expect(m.Up).NotTo(gomega.BeEmpty())
+			g.Expect(m.Down).NotTo(gomega.BeEmpty())
+			driverVersions = append(driverVersions, m.Version)
+		}
+		versions = append(versions, driverVersions)
+	}
+	// Every driver must ship the same migrations
+	g.Expect(versions[1]).To(gomega.Equal(versions[0]))
+	g.Expect(versions[2]).To(gomega.Equal(versions[0]))
+
+	_, err := migration.Load("oracle")
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestMigrator(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+	migrator, db := newSQLiteMigrator(t)
+	latest := migrator.Latest()
+	g.Expect(appliedVersions(t, migrator)).To(gomega.BeEmpty())
+
+	g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+	g.Expect(appliedVersions(t, migrator)).To(gomega.HaveLen(int(latest)))
+	g.Expect(db.Migrator().HasTable("users")).To(gomega.BeTrue())
+	// Applying again is a no-op
+	g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+
+	g.Expect(migrator.Down(ctx)).To(gomega.Succeed())
+	g.Expect(appliedVersions(t, migrator)).To(gomega.HaveLen(int(latest) - 1))
+
+	g.Expect(migrator.To(ctx, 1)).To(gomega.Succeed())
+	g.Expect(appliedVersions(t, migrator)).To(gomega.Equal([]uint{1}))
+	g.Expect(db.Migrator().HasTable("operators")).To(gomega.BeFalse())
+
+	g.Expect(migrator.To(ctx, 0)).To(gomega.Succeed())
+	g.Expect(appliedVersions(t, migrator)).To(gomega.BeEmpty())
+	g.Expect(db.Migrator().HasTable("users")).To(gomega.BeFalse())
+	g.Expect(migrator.Down(ctx)).To(gomega.Succeed())
+
+	g.Expect(migrator.To(ctx, latest+1)).NotTo(gomega.Succeed())
+}
+
+// Databases created from the old schema dump already have the tables
+func TestMigrator_ExistingTables(t *testing.T) {
+	g := gomega.NewWithT(t)
+	migrator, db := newSQLiteMigrator(t)
+	g.Expect(db.Exec("CREATE TABLE users (id integer PRIMARY KEY AUTOINCREMENT, username varchar(30) NOT NULL, email varchar(50) NOT NULL, age integer NOT NULL)").Error).To(gomega.Succeed())
+	g.Expect(db.Exec("INSERT INTO users (username, email, age) VALUES ('alice', 'alice@example.com', 30)").Error).To(gomega.Succeed())
+
+	g.Expect(migrator.Up(context.Background())).To(gomega.Succeed())
+	var count int64
+	g.Expect(db.Table("users").Count(&count).Error).To(gomega.Succeed())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+}
+
+func TestMigrator_UnknownAppliedVersion(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+	migrator, db := newSQLiteMigrator(t)
+	g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+	g.Expect(db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from_a_newer_build', CURRENT_TIMESTAMP)").Error).To(gomega.Succeed())
+
+	g.Expect(migrator.Up(ctx)).NotTo(gomega.Succeed())
+}
+
+// Runs against the database in TEST_MYSQL_DSN, whose tables are dropped and
+// recreated from the original resources/db.sql schema, which has no keys
+func TestMigrator_MySQLBaseline(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	baseline := "CREATE TABLE `users` (`id` int unsigned NOT NULL AUTO_INCREMENT, `username` varchar(30) NOT NULL, `email` varchar(50) NOT NULL, `age` int NOT NULL, PRIMARY KEY (`id`)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci"
+	ctx := context.Background()
+	db, cleanup, err := repository.NewDB(config.DBConfig{Driver: "mysql", DSN: dsn, MaxOpenConns: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasKey := func(name string) bool {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = ?", name).Scan(&count)
+		return count > 0
+	}
+	fromBaseline := func(g *gomega.WithT) {
+		g.Expect(migrator.To(ctx, 0)).To(gomega.Succeed())
+		g.Expect(db.Exec(baseline).Error).To(gomega.Succeed())
+		g.Expect(db.Exec("INSERT INTO users (username, email, age) VALUES ('alice', 'alice@example.com', 30)").Error).To(gomega.Succeed())
+	}
+
+	t.Run("Adopted tables get their keys", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		fromBaseline(g)
+		g.Expect(migrator.To(ctx, 1)).To(gomega.Succeed())
+		for _, key := range []string{"idx_users_username", "idx_users_email", "idx_users_age"} {
+			g.Expect(hasKey(key)).To(gomega.BeTrue(), key)
+		}
+		g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+		var count int64
+		g.Expect(db.Table("users").Count(&count).Error).To(gomega.Succeed())
+		g.Expect(count).To(gomega.Equal(int64(1)))
+	})
+
+	// Databases adopted before 0001 added the keys are left without them
+	t.Run("Unique keys do not need the plain ones", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		fromBaseline(g)
+		g.Expect(migrator.To(ctx, 4)).To(gomega.Succeed())
+		g.Expect(db.Exec("ALTER TABLE `users` DROP KEY `idx_users_email`, DROP KEY `idx_users_username`").Error).To(gomega.Succeed())
+		g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+		g.Expect(hasKey("idx_users_active_email_unique")).To(gomega.BeTrue())
+	})
+}
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `username` varchar(30) NOT NULL,
+  `email` varchar(50) NOT NULL,
+  `age` int NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_users_username` (`username`),
+  KEY `idx_users_email` (`email`),
+  KEY `idx_users_age` (`age`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+-- Tables created from the original resources/db.sql lack the keys above.
+-- MySQL has no ADD KEY IF NOT EXISTS, so add the missing ones with a
+-- statement built from information_schema.
+SET @atmail_missing_keys = CONCAT_WS(', ',
+  IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_username'), NULL, 'ADD KEY `idx_users_username` (`username`)'),
+  IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_email'), NULL, 'ADD KEY `idx_users_email` (`email`)'),
+  IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_age'), NULL, 'ADD KEY `idx_users_age` (`age`)')
+);
+SET @atmail_sql = IF(@atmail_missing_keys = '', 'DO 0', CONCAT('ALTER TABLE `users` ', @atmail_missing_keys));
+PREPARE atmail_stmt FROM @atmail_sql;
+EXECUTE atmail_stmt;
+DEALLOCATE PREPARE atmail_stmt;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `operator_id` int unsigned NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_refresh_tokens_token_hash` (`token_hash`),
+  KEY `idx_refresh_tokens_operator_id` (`operator_id`),
+  CONSTRAINT `fk_refresh_tokens_operator` FOREIGN KEY (`operator_id`) REFERENCES `operators` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
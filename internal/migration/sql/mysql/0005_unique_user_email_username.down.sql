@@ -0,0 +1,5 @@
+ALTER TABLE `users`
+  ADD KEY `idx_users_email` (`email`),
+  ADD KEY `idx_users_username` (`username`),
+  DROP KEY `idx_users_email_unique`,
+  DROP KEY `idx_users_username_unique`;
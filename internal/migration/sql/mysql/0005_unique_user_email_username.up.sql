@@ -0,0 +1,11 @@
+-- Fails if users already share an email or username; resolve those first.
+-- The utf8mb4_0900_ai_ci collation makes both indexes case-insensitive.
+-- The plain keys are only dropped if present, as databases that adopted the
+-- original resources/db.sql before 0001 added them have none.
+SET @atmail_sql = CONCAT('ALTER TABLE `users` ADD UNIQUE KEY `idx_users_email_unique` (`email`), ADD UNIQUE KEY `idx_users_username_unique` (`username`)',
+  IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_email'), ', DROP KEY `idx_users_email`', ''),
+  IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_username'), ', DROP KEY `idx_users_username`', '')
+);
+PREPARE atmail_stmt FROM @atmail_sql;
+EXECUTE atmail_stmt;
+DEALLOCATE PREPARE atmail_stmt;
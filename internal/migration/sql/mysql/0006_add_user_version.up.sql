@@ -0,0 +1,2 @@
+-- Incremented by every update so concurrent writers can detect each other
+ALTER TABLE `users` ADD COLUMN `version` int unsigned NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Existing users get the time of the migration
+ALTER TABLE `users`
+  ADD COLUMN `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD COLUMN `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,9 @@
+-- One row per table, bumped by every write to it, so clients can tell whether
+-- anything in a collection changed without reading it
+CREATE TABLE IF NOT EXISTS `change_markers` (
+  `table_name` varchar(64) NOT NULL,
+  `version` bigint unsigned NOT NULL,
+  `changed_at` datetime NOT NULL,
+  PRIMARY KEY (`table_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+INSERT IGNORE INTO `change_markers` (`table_name`, `version`, `changed_at`) VALUES ('users', 1, CURRENT_TIMESTAMP);
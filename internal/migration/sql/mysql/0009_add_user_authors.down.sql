@@ -0,0 +1,5 @@
+ALTER TABLE `users`
+  DROP KEY `idx_users_created_at`,
+  DROP KEY `idx_users_updated_at`,
+  DROP COLUMN `created_by`,
+  DROP COLUMN `updated_by`;
@@ -0,0 +1,6 @@
+-- Principals such as operator:3; unknown for users created before this
+ALTER TABLE `users`
+  ADD COLUMN `created_by` varchar(64) NULL,
+  ADD COLUMN `updated_by` varchar(64) NULL,
+  ADD KEY `idx_users_created_at` (`created_at`),
+  ADD KEY `idx_users_updated_at` (`updated_at`);
@@ -0,0 +1,13 @@
+-- Deleted users are purged, as they may share values with other users
+DELETE FROM `users` WHERE `deleted_at` IS NOT NULL;
+ALTER TABLE `users`
+  ADD UNIQUE KEY `idx_users_email_unique` (`email`),
+  ADD UNIQUE KEY `idx_users_username_unique` (`username`),
+  DROP KEY `idx_users_active_email_unique`,
+  DROP KEY `idx_users_active_username_unique`,
+  DROP KEY `idx_users_email`,
+  DROP KEY `idx_users_username`,
+  DROP KEY `idx_users_deleted_at`,
+  DROP COLUMN `active_email`,
+  DROP COLUMN `active_username`,
+  DROP COLUMN `deleted_at`;
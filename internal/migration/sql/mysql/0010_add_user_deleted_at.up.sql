@@ -0,0 +1,14 @@
+-- Emails and usernames stay unique among users that are not deleted. MySQL
+-- has no partial indexes, so the unique keys cover generated columns that
+-- are NULL once a user is deleted.
+ALTER TABLE `users`
+  ADD COLUMN `deleted_at` datetime NULL,
+  ADD COLUMN `active_email` varchar(50) GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, `email`, NULL)) VIRTUAL,
+  ADD COLUMN `active_username` varchar(30) GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, `username`, NULL)) VIRTUAL,
+  ADD KEY `idx_users_deleted_at` (`deleted_at`),
+  ADD KEY `idx_users_email` (`email`),
+  ADD KEY `idx_users_username` (`username`),
+  ADD UNIQUE KEY `idx_users_active_email_unique` (`active_email`),
+  ADD UNIQUE KEY `idx_users_active_username_unique` (`active_username`),
+  DROP KEY `idx_users_email_unique`,
+  DROP KEY `idx_users_username_unique`;
@@ -0,0 +1,16 @@
+-- Append-only: the application never updates or deletes entries, and they
+-- outlive purged users. changes is a JSON object of before/after values.
+CREATE TABLE IF NOT EXISTS `user_audit` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int unsigned NOT NULL,
+  `action` varchar(16) NOT NULL,
+  `actor` varchar(64) NULL,
+  `request_id` varchar(128) NULL,
+  `source_ip` varchar(45) NULL,
+  `changes` text NOT NULL,
+  `occurred_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_audit_user_id` (`user_id`, `id`),
+  KEY `idx_user_audit_actor` (`actor`),
+  KEY `idx_user_audit_occurred_at` (`occurred_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `refresh_tokens`
+  DROP KEY `idx_refresh_tokens_family_id`,
+  DROP COLUMN `family_id`;
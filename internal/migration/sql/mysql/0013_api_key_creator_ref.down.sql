@@ -0,0 +1,2 @@
+UPDATE `api_keys` SET `created_by` = SUBSTRING_INDEX(`created_by`, ':', -1);
+ALTER TABLE `api_keys` MODIFY `created_by` int unsigned NOT NULL;
@@ -0,0 +1,3 @@
+-- created_by becomes a principal such as operator:1. Keys minted before this
+-- keep the bare ID, which may be an operator's or an API key's.
+ALTER TABLE `api_keys` MODIFY `created_by` varchar(64) NOT NULL;
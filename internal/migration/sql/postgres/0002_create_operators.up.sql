@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS operators (
+  id serial PRIMARY KEY,
+  username varchar(30) NOT NULL,
+  password_hash varchar(255) NOT NULL,
+  role varchar(20) NOT NULL DEFAULT 'helpdesk',
+  disabled boolean NOT NULL DEFAULT false,
+  password_changed_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operators_username ON operators (username);
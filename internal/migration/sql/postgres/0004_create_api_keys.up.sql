@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  id serial PRIMARY KEY,
+  name varchar(100) NOT NULL,
+  prefix char(8) NOT NULL,
+  key_hash char(64) NOT NULL,
+  scopes varchar(255) NOT NULL,
+  expires_at timestamptz DEFAULT NULL,
+  last_used_at timestamptz DEFAULT NULL,
+  revoked_at timestamptz DEFAULT NULL,
+  created_by integer NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
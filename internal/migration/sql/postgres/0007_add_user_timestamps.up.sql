@@ -0,0 +1,4 @@
+-- Existing users get the time of the migration
+ALTER TABLE users
+  ADD COLUMN created_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
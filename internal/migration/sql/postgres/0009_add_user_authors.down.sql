@@ -0,0 +1,5 @@
+DROP INDEX idx_users_created_at;
+DROP INDEX idx_users_updated_at;
+ALTER TABLE users
+  DROP COLUMN created_by,
+  DROP COLUMN updated_by;
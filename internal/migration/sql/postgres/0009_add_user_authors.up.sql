@@ -0,0 +1,6 @@
+-- Principals such as operator:3; unknown for users created before this
+ALTER TABLE users
+  ADD COLUMN created_by varchar(64) NULL,
+  ADD COLUMN updated_by varchar(64) NULL;
+CREATE INDEX idx_users_created_at ON users (created_at);
+CREATE INDEX idx_users_updated_at ON users (updated_at);
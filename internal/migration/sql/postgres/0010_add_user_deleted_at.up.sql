@@ -0,0 +1,7 @@
+-- Emails and usernames stay unique among users that are not deleted
+ALTER TABLE users ADD COLUMN deleted_at timestamptz NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+DROP INDEX idx_users_email_unique;
+DROP INDEX idx_users_username_unique;
+CREATE UNIQUE INDEX idx_users_email_unique ON users (lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_username_unique ON users (lower(username)) WHERE deleted_at IS NULL;
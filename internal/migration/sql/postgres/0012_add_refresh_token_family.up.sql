@@ -0,0 +1,5 @@
+-- Tokens issued by rotating one refresh token share its family, so reuse of
+-- a rotated token can revoke them all. Tokens from before this have none and
+-- are the family of their own hash.
+ALTER TABLE refresh_tokens ADD COLUMN family_id varchar(64) NULL;
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
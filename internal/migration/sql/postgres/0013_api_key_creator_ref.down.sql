@@ -0,0 +1 @@
+ALTER TABLE api_keys ALTER COLUMN created_by TYPE integer USING regexp_replace(created_by, '^.*:', '')::integer;
@@ -0,0 +1,3 @@
+-- created_by becomes a principal such as operator:1. Keys minted before this
+-- keep the bare ID, which may be an operator's or an API key's.
+ALTER TABLE api_keys ALTER COLUMN created_by TYPE varchar(64) USING created_by::text;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS operators (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  username varchar(30) NOT NULL,
+  password_hash varchar(255) NOT NULL,
+  role varchar(20) NOT NULL DEFAULT 'helpdesk',
+  disabled boolean NOT NULL DEFAULT 0,
+  password_changed_at datetime NOT NULL,
+  created_at datetime NOT NULL,
+  updated_at datetime NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operators_username ON operators (username);
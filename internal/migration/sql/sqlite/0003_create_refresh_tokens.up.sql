@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  operator_id integer NOT NULL REFERENCES operators (id) ON DELETE CASCADE,
+  token_hash char(64) NOT NULL,
+  expires_at datetime NOT NULL,
+  revoked_at datetime DEFAULT NULL,
+  created_at datetime NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_operator_id ON refresh_tokens (operator_id);
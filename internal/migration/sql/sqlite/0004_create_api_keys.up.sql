@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar(100) NOT NULL,
+  prefix char(8) NOT NULL,
+  key_hash char(64) NOT NULL,
+  scopes varchar(255) NOT NULL,
+  expires_at datetime DEFAULT NULL,
+  last_used_at datetime DEFAULT NULL,
+  revoked_at datetime DEFAULT NULL,
+  created_by integer NOT NULL,
+  created_at datetime NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_email_unique;
+DROP INDEX IF EXISTS idx_users_username_unique;
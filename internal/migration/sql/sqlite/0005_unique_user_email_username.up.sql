@@ -0,0 +1,4 @@
+-- Fails if users already share an email or username; resolve those first.
+-- Indexed in lower case so the constraint ignores case like MySQL does.
+CREATE UNIQUE INDEX idx_users_email_unique ON users (lower(email));
+CREATE UNIQUE INDEX idx_users_username_unique ON users (lower(username));
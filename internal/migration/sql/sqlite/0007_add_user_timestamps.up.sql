@@ -0,0 +1,5 @@
+-- SQLite cannot add a column defaulting to the current time, so existing
+-- users are given it afterwards
+ALTER TABLE users ADD COLUMN created_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN updated_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE users SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
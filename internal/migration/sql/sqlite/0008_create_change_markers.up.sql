@@ -0,0 +1,8 @@
+-- One row per table, bumped by every write to it, so clients can tell whether
+-- anything in a collection changed without reading it
+CREATE TABLE IF NOT EXISTS change_markers (
+  table_name varchar(64) PRIMARY KEY,
+  version integer NOT NULL,
+  changed_at datetime NOT NULL
+);
+INSERT INTO change_markers (table_name, version, changed_at) VALUES ('users', 1, CURRENT_TIMESTAMP);
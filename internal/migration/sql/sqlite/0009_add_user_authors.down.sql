@@ -0,0 +1,4 @@
+DROP INDEX idx_users_created_at;
+DROP INDEX idx_users_updated_at;
+ALTER TABLE users DROP COLUMN created_by;
+ALTER TABLE users DROP COLUMN updated_by;
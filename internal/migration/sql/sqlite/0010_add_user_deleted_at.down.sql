@@ -0,0 +1,8 @@
+-- Deleted users are purged, as they may share values with other users
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DROP INDEX idx_users_email_unique;
+DROP INDEX idx_users_username_unique;
+DROP INDEX idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+CREATE UNIQUE INDEX idx_users_email_unique ON users (lower(email));
+CREATE UNIQUE INDEX idx_users_username_unique ON users (lower(username));
@@ -0,0 +1,15 @@
+-- Append-only: the application never updates or deletes entries, and they
+-- outlive purged users. changes is a JSON object of before/after values.
+CREATE TABLE IF NOT EXISTS user_audit (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  action varchar(16) NOT NULL,
+  actor varchar(64) NULL,
+  request_id varchar(128) NULL,
+  source_ip varchar(45) NULL,
+  changes text NOT NULL,
+  occurred_at datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_audit_user_id ON user_audit (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_user_audit_actor ON user_audit (actor);
+CREATE INDEX IF NOT EXISTS idx_user_audit_occurred_at ON user_audit (occurred_at);
@@ -0,0 +1,17 @@
+CREATE TABLE api_keys_old (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar(100) NOT NULL,
+  prefix char(8) NOT NULL,
+  key_hash char(64) NOT NULL,
+  scopes varchar(255) NOT NULL,
+  expires_at datetime DEFAULT NULL,
+  last_used_at datetime DEFAULT NULL,
+  revoked_at datetime DEFAULT NULL,
+  created_by integer NOT NULL,
+  created_at datetime NOT NULL
+);
+INSERT INTO api_keys_old (id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at)
+  SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, CAST(substr(created_by, instr(created_by, ':') + 1) AS INTEGER), created_at FROM api_keys;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
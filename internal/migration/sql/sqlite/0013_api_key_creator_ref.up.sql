@@ -0,0 +1,20 @@
+-- created_by becomes a principal such as operator:1. Keys minted before this
+-- keep the bare ID, which may be an operator's or an API key's. SQLite cannot
+-- change a column's type, so the table is rebuilt.
+CREATE TABLE api_keys_new (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar(100) NOT NULL,
+  prefix char(8) NOT NULL,
+  key_hash char(64) NOT NULL,
+  scopes varchar(255) NOT NULL,
+  expires_at datetime DEFAULT NULL,
+  last_used_at datetime DEFAULT NULL,
+  revoked_at datetime DEFAULT NULL,
+  created_by varchar(64) NOT NULL,
+  created_at datetime NOT NULL
+);
+INSERT INTO api_keys_new (id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at)
+  SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, CAST(created_by AS TEXT), created_at FROM api_keys;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
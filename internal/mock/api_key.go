@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/api_key_service.go
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	model "atmail/internal/model"
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockApiKeyService is a mock of ApiKeyService interface.
+type MockApiKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeyServiceMockRecorder
+}
+
+// MockApiKeyServiceMockRecorder is the mock recorder for MockApiKeyService.
+type MockApiKeyServiceMockRecorder struct {
+	mock *MockApiKeyService
+}
+
+// NewMockApiKeyService creates a new mock instance.
+func NewMockApiKeyService(ctrl *gomock.Controller) *MockApiKeyService {
+	mock := &MockApiKeyService{ctrl: ctrl}
+	mock.recorder = &MockApiKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeyService) EXPECT() *MockApiKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockApiKeyService) Authenticate(ctx context.Context, key string) (*model.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, key)
+	ret0, _ := ret[0].(*model.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockApiKeyServiceMockRecorder) Authenticate(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockApiKeyService)(nil).Authenticate), ctx, key)
+}
+
+// Create mocks base method.
+func (m *MockApiKeyService) Create(ctx context.Context, req model.ApiKeyRequest, creator *model.Principal) (*model.ApiKeyCreated, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, req, creator)
+	ret0, _ := ret[0].(*model.ApiKeyCreated)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockApiKeyServiceMockRecorder) Create(ctx, req, creator interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockApiKeyService)(nil).Create), ctx, req, creator)
+}
+
+// GetAll mocks base method.
+func (m *MockApiKeyService) GetAll(ctx context.Context) (*[]model.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].(*[]model.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockApiKeyServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockApiKeyService)(nil).GetAll), ctx)
+}
+
+// Revoke mocks base method.
+func (m *MockApiKeyService) Revoke(ctx context.Context, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockApiKeyServiceMockRecorder) Revoke(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockApiKeyService)(nil).Revoke), ctx, id)
+}
@@ -0,0 +1,152 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/operator_service.go
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	model "atmail/internal/model"
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOperatorService is a mock of OperatorService interface.
+type MockOperatorService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperatorServiceMockRecorder
+}
+
+// MockOperatorServiceMockRecorder is the mock recorder for MockOperatorService.
+type MockOperatorServiceMockRecorder struct {
+	mock *MockOperatorService
+}
+
+// NewMockOperatorService creates a new mock instance.
+func NewMockOperatorService(ctrl *gomock.Controller) *MockOperatorService {
+	mock := &MockOperatorService{ctrl: ctrl}
+	mock.recorder = &MockOperatorServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOperatorService) EXPECT() *MockOperatorServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockOperatorService) Authenticate(ctx context.Context, username, password string) (*model.Operator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, username, password)
+	ret0, _ := ret[0].(*model.Operator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockOperatorServiceMockRecorder) Authenticate(ctx, username, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockOperatorService)(nil).Authenticate), ctx, username, password)
+}
+
+// Bootstrap mocks base method.
+func (m *MockOperatorService) Bootstrap(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bootstrap", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Bootstrap indicates an expected call of Bootstrap.
+func (mr *MockOperatorServiceMockRecorder) Bootstrap(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bootstrap", reflect.TypeOf((*MockOperatorService)(nil).Bootstrap), ctx)
+}
+
+// Create mocks base method.
+func (m *MockOperatorService) Create(ctx context.Context, req model.OperatorRequest) (*model.Operator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, req)
+	ret0, _ := ret[0].(*model.Operator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockOperatorServiceMockRecorder) Create(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOperatorService)(nil).Create), ctx, req)
+}
+
+// Disable mocks base method.
+func (m *MockOperatorService) Disable(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockOperatorServiceMockRecorder) Disable(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockOperatorService)(nil).Disable), ctx, username)
+}
+
+// Get mocks base method.
+func (m *MockOperatorService) Get(ctx context.Context, id uint) (*model.Operator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*model.Operator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockOperatorServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockOperatorService)(nil).Get), ctx, id)
+}
+
+// GetByUsername mocks base method.
+func (m *MockOperatorService) GetByUsername(ctx context.Context, username string) (*model.Operator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUsername", ctx, username)
+	ret0, _ := ret[0].(*model.Operator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUsername indicates an expected call of GetByUsername.
+func (mr *MockOperatorServiceMockRecorder) GetByUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUsername", reflect.TypeOf((*MockOperatorService)(nil).GetByUsername), ctx, username)
+}
+
+// RotatePassword mocks base method.
+func (m *MockOperatorService) RotatePassword(ctx context.Context, req model.OperatorRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotatePassword", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotatePassword indicates an expected call of RotatePassword.
+func (mr *MockOperatorServiceMockRecorder) RotatePassword(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotatePassword", reflect.TypeOf((*MockOperatorService)(nil).RotatePassword), ctx, req)
+}
+
+// SetRole mocks base method.
+func (m *MockOperatorService) SetRole(ctx context.Context, username, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, username, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockOperatorServiceMockRecorder) SetRole(ctx, username, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockOperatorService)(nil).SetRole), ctx, username, role)
+}
@@ -6,6 +6,7 @@ package mock_service
 
 import (
 	model "atmail/internal/model"
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,121 +35,206 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangeMarker mocks base method.
+func (m *MockUserService) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeMarker", ctx)
+	ret0, _ := ret[0].(*model.ChangeMarker)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeMarker indicates an expected call of ChangeMarker.
+func (mr *MockUserServiceMockRecorder) ChangeMarker(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeMarker", reflect.TypeOf((*MockUserService)(nil).ChangeMarker), ctx)
+}
+
 // Delete mocks base method.
-func (m *MockUserService) Delete(id uint) error {
+func (m *MockUserService) Delete(ctx context.Context, id uint, version *uint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockUserServiceMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserService)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserService)(nil).Delete), ctx, id, version)
 }
 
 // Get mocks base method.
-func (m *MockUserService) Get(id uint) (*model.User, int, error) {
+func (m *MockUserService) Get(ctx context.Context, id uint) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", id)
+	ret := m.ctrl.Call(m, "Get", ctx, id)
 	ret0, _ := ret[0].(*model.User)
-	ret1, _ := ret[1].(int)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockUserServiceMockRecorder) Get(id interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserService)(nil).Get), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserService)(nil).Get), ctx, id)
 }
 
 // GetAll mocks base method.
-func (m *MockUserService) GetAll() (*[]model.User, error) {
+func (m *MockUserService) GetAll(ctx context.Context) (*[]model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", ctx)
 	ret0, _ := ret[0].(*[]model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockUserServiceMockRecorder) GetAll() *gomock.Call {
+func (mr *MockUserServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockUserService)(nil).GetAll), ctx)
+}
+
+// List mocks base method.
+func (m *MockUserService) List(ctx context.Context, query model.UserQuery) (*model.UserList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, query)
+	ret0, _ := ret[0].(*model.UserList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserServiceMockRecorder) List(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserService)(nil).List), ctx, query)
+}
+
+// Patch mocks base method.
+func (m *MockUserService) Patch(ctx context.Context, id uint, patch model.UserPatch, version *uint) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, patch, version)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockUserServiceMockRecorder) Patch(ctx, id, patch, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockUserService)(nil).Patch), ctx, id, patch, version)
+}
+
+// Purge mocks base method.
+func (m *MockUserService) Purge(ctx context.Context, id uint, version *uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockUserServiceMockRecorder) Purge(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockUserService)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockUserService)(nil).Purge), ctx, id, version)
+}
+
+// Restore mocks base method.
+func (m *MockUserService) Restore(ctx context.Context, id uint, version *uint) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id, version)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockUserServiceMockRecorder) Restore(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockUserService)(nil).Restore), ctx, id, version)
 }
 
 // Save mocks base method.
-func (m *MockUserService) Save(req model.UserRequest) (*model.User, error) {
+func (m *MockUserService) Save(ctx context.Context, req model.UserRequest) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", req)
+	ret := m.ctrl.Call(m, "Save", ctx, req)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockUserServiceMockRecorder) Save(req interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Save(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockUserService)(nil).Save), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockUserService)(nil).Save), ctx, req)
 }
 
 // Update mocks base method.
-func (m *MockUserService) Update(req model.User) (*model.User, error) {
+func (m *MockUserService) Update(ctx context.Context, id uint, req model.UserUpdateRequest, version *uint) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", req)
+	ret := m.ctrl.Call(m, "Update", ctx, id, req, version)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockUserServiceMockRecorder) Update(req interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Update(ctx, id, req, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserService)(nil).Update), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserService)(nil).Update), ctx, id, req, version)
 }
 
 // ValidateExistingUser mocks base method.
-func (m *MockUserService) ValidateExistingUser(req model.User) (int, error) {
+func (m *MockUserService) ValidateExistingUser(ctx context.Context, id uint, req model.UserUpdateRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateExistingUser", req)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ValidateExistingUser", ctx, id, req)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // ValidateExistingUser indicates an expected call of ValidateExistingUser.
-func (mr *MockUserServiceMockRecorder) ValidateExistingUser(req interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) ValidateExistingUser(ctx, id, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateExistingUser", reflect.TypeOf((*MockUserService)(nil).ValidateExistingUser), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateExistingUser", reflect.TypeOf((*MockUserService)(nil).ValidateExistingUser), ctx, id, req)
 }
 
 // ValidateID mocks base method.
-func (m *MockUserService) ValidateID(id uint) (int, error) {
+func (m *MockUserService) ValidateID(ctx context.Context, id uint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateID", id)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ValidateID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // ValidateID indicates an expected call of ValidateID.
-func (mr *MockUserServiceMockRecorder) ValidateID(id interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) ValidateID(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateID", reflect.TypeOf((*MockUserService)(nil).ValidateID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateID", reflect.TypeOf((*MockUserService)(nil).ValidateID), ctx, id)
 }
 
 // ValidateNewUser mocks base method.
-func (m *MockUserService) ValidateNewUser(req model.UserRequest) error {
+func (m *MockUserService) ValidateNewUser(ctx context.Context, req model.UserRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateNewUser", req)
+	ret := m.ctrl.Call(m, "ValidateNewUser", ctx, req)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ValidateNewUser indicates an expected call of ValidateNewUser.
-func (mr *MockUserServiceMockRecorder) ValidateNewUser(req interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) ValidateNewUser(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateNewUser", reflect.TypeOf((*MockUserService)(nil).ValidateNewUser), ctx, req)
+}
+
+// ValidateQuery mocks base method.
+func (m *MockUserService) ValidateQuery(ctx context.Context, query model.UserQuery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateQuery", ctx, query)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateQuery indicates an expected call of ValidateQuery.
+func (mr *MockUserServiceMockRecorder) ValidateQuery(ctx, query interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateNewUser", reflect.TypeOf((*MockUserService)(nil).ValidateNewUser), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateQuery", reflect.TypeOf((*MockUserService)(nil).ValidateQuery), ctx, query)
 }
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+type ApiKey struct {
+	ID         uint         `json:"id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedBy  string       `json:"created_by"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type ApiKeyRequest struct {
+	Name      string       `json:"name"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+// Returned once when a key is created; only a hash of Key is stored
+type ApiKeyCreated struct {
+	ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Actions recorded in the user audit log
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// Value of a field before and after a change; nil where it had none
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// One change to a user. Entries are never changed or removed.
+type UserAuditEntry struct {
+	ID     uint   `json:"id"`
+	UserID uint   `json:"user_id"`
+	Action string `json:"action"`
+	// Principal that made the change, such as operator:3
+	Actor      string                 `json:"actor,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	SourceIP   string                 `json:"source_ip,omitempty"`
+	Changes    map[string]FieldChange `json:"changes"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Query parameters accepted by GET /audit and GET /users/{id}/history
+type AuditQuery struct {
+	Limit     int    `form:"limit"`
+	Cursor    string `form:"cursor"`
+	UserID    *uint  `form:"user_id"`
+	Action    string `form:"action"`
+	Actor     string `form:"actor"`
+	RequestID string `form:"request_id"`
+	// RFC 3339 times; from is inclusive and to exclusive
+	From *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// A page of audit entries, newest first
+type UserAuditList struct {
+	Items      []UserAuditEntry `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Next       string           `json:"next,omitempty"`
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// How many times a collection has changed and when it last did
+type ChangeMarker struct {
+	Version   uint64
+	ChangedAt time.Time
+}
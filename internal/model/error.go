@@ -1,5 +0,0 @@
-package model
-
-type Error struct {
-	Error string `json:"error"`
-}
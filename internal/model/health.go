@@ -0,0 +1,19 @@
+package model
+
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+)
+
+type Health struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+type HealthCheck struct {
+	Name      string                 `json:"name"`
+	Status    string                 `json:"status"`
+	LatencyMs float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
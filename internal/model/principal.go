@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"strconv"
+)
+
+type Permission string
+
+const (
+	UsersRead   Permission = "users:read"
+	UsersWrite  Permission = "users:write"
+	UsersDelete Permission = "users:delete"
+
+	ApiKeysManage Permission = "api_keys:manage"
+
+	AuditRead Permission = "audit:read"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleHelpdesk = "helpdesk"
+)
+
+// Permissions granted by each role
+var RolePermissions = map[string][]Permission{
+	RoleAdmin:    {UsersRead, UsersWrite, UsersDelete, ApiKeysManage, AuditRead},
+	RoleHelpdesk: {UsersRead},
+}
+
+const (
+	PrincipalOperator = "operator"
+	PrincipalApiKey   = "api_key"
+)
+
+// Authenticated caller of a request
+type Principal struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Kind        string       `json:"kind"`
+	Role        string       `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func (p *Principal) HasPermission(permission Permission) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func NewOperatorPrincipal(operator *Operator) *Principal {
+	return &Principal{
+		ID:          operator.ID,
+		Name:        operator.Username,
+		Kind:        PrincipalOperator,
+		Role:        operator.Role,
+		Permissions: RolePermissions[operator.Role],
+	}
+}
+
+// Stable reference to the principal, such as operator:3, recorded as the
+// author of changes. Operators and API keys are numbered separately.
+func (p *Principal) Ref() string {
+	return p.Kind + ":" + strconv.FormatUint(uint64(p.ID), 10)
+}
+
+type principalKey struct{}
+
+// Carry the authenticated principal to the services
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal the request was authenticated as, nil when it was not
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,32 @@
+package model
+
+// Problem types beyond the plain HTTP status
+const (
+	// The problem is fully described by the status code
+	ProblemTypeDefault = "about:blank"
+	// Errors lists the invalid request fields
+	ProblemTypeValidation = "urn:atmail:problem:validation"
+	// Errors lists the fields whose values are already taken
+	ProblemTypeConflict = "urn:atmail:problem:conflict"
+)
+
+// Error response in the RFC 7807 problem details format, served as
+// application/problem+json
+type Problem struct {
+	Type   string `json:"type" example:"urn:atmail:problem:validation"`
+	Title  string `json:"title" example:"Invalid request"`
+	Status int    `json:"status" example:"400"`
+	Detail string `json:"detail,omitempty" example:"invalid email; invalid age"`
+	// Path of the request that failed
+	Instance string `json:"instance,omitempty" example:"/atmail/users"`
+	// Also sent as the X-Request-ID header
+	RequestID string       `json:"request_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field string `json:"field" example:"email"`
+	// Machine-readable reason, such as email_invalid or username_exists
+	Code    string `json:"code" example:"email_invalid"`
+	Message string `json:"message" example:"invalid email"`
+}
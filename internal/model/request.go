@@ -0,0 +1,21 @@
+package model
+
+import "context"
+
+// Where a request came from, recorded with the changes it makes
+type RequestMeta struct {
+	RequestID string
+	SourceIP  string
+}
+
+type requestMetaKey struct{}
+
+func ContextWithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// Metadata of the request, empty outside of one
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
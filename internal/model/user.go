@@ -1,10 +1,22 @@
 package model
 
+import "time"
+
 type User struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Age      int    `json:"age"`
+	// Incremented by every change and sent as the ETag
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Principals that created and last changed the user, such as
+	// operator:3. Absent for changes made before they were recorded.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// Set when the user is deleted, until it is restored or purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type UserRequest struct {
@@ -12,3 +24,42 @@ type UserRequest struct {
 	Email    string `json:"email"`
 	Age      int    `json:"age"`
 }
+
+// Body of PUT /users/{id}. The ID comes from the path only.
+type UserUpdateRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Age      int    `json:"age"`
+}
+
+// Body of PATCH /users/{id}: a JSON Merge Patch or JSON Patch document,
+// told apart by its media type
+type UserPatch struct {
+	MediaType string
+	Document  []byte
+}
+
+// Query parameters accepted by GET /users
+type UserQuery struct {
+	Limit          int    `form:"limit"`
+	Cursor         string `form:"cursor"`
+	Sort           string `form:"sort"`
+	Order          string `form:"order"`
+	UsernamePrefix string `form:"username_prefix"`
+	EmailDomain    string `form:"email_domain"`
+	MinAge         *int   `form:"min_age"`
+	MaxAge         *int   `form:"max_age"`
+	// RFC 3339 times, both exclusive
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	UpdatedBefore *time.Time `form:"updated_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Include deleted users; only for principals allowed to delete them
+	IncludeDeleted bool `form:"include_deleted"`
+}
+
+// A single page of users
+type UserList struct {
+	Items      []User `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
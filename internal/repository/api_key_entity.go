@@ -0,0 +1,22 @@
+package repository
+
+import "time"
+
+type ApiKey struct {
+	ID         uint
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	// Principal that minted the key, such as operator:1. Keys from before
+	// migration 13 hold the bare ID of an operator or API key.
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ApiKeyRepository kept in process memory, for running without a database
+type apiKeyMemoryRepository struct {
+	mu     sync.RWMutex
+	keys   map[uint]ApiKey
+	nextID uint
+}
+
+func NewApiKeyMemoryRepository() ApiKeyRepository {
+	repo := new(apiKeyMemoryRepository)
+	repo.keys = make(map[uint]ApiKey)
+	repo.nextID = 1
+	return repo
+}
+
+func (a *apiKeyMemoryRepository) Get(ctx context.Context, id uint) (*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	key, ok := a.keys[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &key, nil
+}
+
+func (a *apiKeyMemoryRepository) GetAll(ctx context.Context) (*[]ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	keys := make([]ApiKey, 0, len(a.keys))
+	for _, key := range a.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return &keys, nil
+}
+
+func (a *apiKeyMemoryRepository) GetByPrefix(ctx context.Context, prefix string) (*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, key := range a.keys {
+		if key.Prefix == prefix {
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (a *apiKeyMemoryRepository) Revoke(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.keys[id]; ok && key.RevokedAt == nil {
+		revokedAt := time.Now()
+		key.RevokedAt = &revokedAt
+		a.keys[id] = key
+	}
+	return nil
+}
+
+func (a *apiKeyMemoryRepository) Save(ctx context.Context, key ApiKey) (*ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, other := range a.keys {
+		if other.Prefix == key.Prefix {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+	key.ID = a.nextID
+	a.nextID++
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	a.keys[key.ID] = key
+	return &key, nil
+}
+
+func (a *apiKeyMemoryRepository) Touch(ctx context.Context, id uint, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+		a.keys[id] = key
+	}
+	return nil
+}
+
+// API key repository chosen by STORAGE: database (default) or memory
+func SelectApiKeyRepository(db *gorm.DB, cfg config.DBConfig) ApiKeyRepository {
+	if cfg.InMemory() {
+		return NewApiKeyMemoryRepository()
+	}
+	return NewApiKeyRepository(db, cfg)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+type ApiKeyRepository interface {
+	Get(ctx context.Context, id uint) (*ApiKey, error)
+	GetAll(ctx context.Context) (*[]ApiKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*ApiKey, error)
+	Revoke(ctx context.Context, id uint) error
+	Save(ctx context.Context, key ApiKey) (*ApiKey, error)
+	Touch(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+func NewApiKeyRepository(db *gorm.DB, cfg config.DBConfig) ApiKeyRepository {
+	repo := new(apiKeyRepository)
+	repo.db = db
+	repo.timeout = cfg.QueryTimeout
+	return repo
+}
+
+func (a *apiKeyRepository) Get(ctx context.Context, id uint) (*ApiKey, error) {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	var key ApiKey
+	key.ID = id
+	if err := db.First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (a *apiKeyRepository) GetAll(ctx context.Context) (*[]ApiKey, error) {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	var keys []ApiKey
+	if err := db.Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+func (a *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*ApiKey, error) {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	var key ApiKey
+	if err := db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (a *apiKeyRepository) Revoke(ctx context.Context, id uint) error {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	return db.Model(&ApiKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (a *apiKeyRepository) Save(ctx context.Context, key ApiKey) (*ApiKey, error) {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	if err := db.Create(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (a *apiKeyRepository) Touch(ctx context.Context, id uint, usedAt time.Time) error {
+	db, cancel := withContext(ctx, a.db, a.timeout)
+	defer cancel()
+	return db.Model(&ApiKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
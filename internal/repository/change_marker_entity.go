@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Counter bumped in the same transaction as every write to a table
+type ChangeMarker struct {
+	Table     string `gorm:"column:table_name;primaryKey"`
+	Version   uint64
+	ChangedAt time.Time
+}
+
+func (ChangeMarker) TableName() string {
+	return "change_markers"
+}
+
+// Bump the change marker of table. Taking its row lock first also serialises
+// concurrent writes to the table. A missing row is created rather than
+// leaving the write with no marker change for conditional GETs to see.
+func touchChangeMarker(tx *gorm.DB, table string, changedAt time.Time) error {
+	result := tx.Model(&ChangeMarker{}).
+		Where("table_name = ?", table).
+		Updates(map[string]interface{}{
+			"version":    gorm.Expr("version + 1"),
+			"changed_at": changedAt,
+		})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// Another writer may create the row first, in which case bump theirs
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "table_name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"version":    gorm.Expr("change_markers.version + 1"),
+			"changed_at": changedAt,
+		}),
+	}).Create(&ChangeMarker{Table: table, Version: 1, ChangedAt: changedAt}).Error
+}
+
+// Current time as stored: UTC, to the second
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/metrics"
+	"atmail/internal/tracing"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open the connection pool described by cfg. The returned function closes it.
+func NewDB(cfg config.DBConfig) (*gorm.DB, func(), error) {
+	logLevel := logger.Silent
+	if cfg.Log {
+		logLevel = logger.Info
+	}
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	metrics.RegisterDB(sqlDB, "atmail")
+
+	log.Infoln("Database connection established")
+	cleanup := func() {
+		if err := sqlDB.Close(); err != nil {
+			log.Errorf("Error closing database: %s", err.Error())
+		}
+	}
+	return db, cleanup, nil
+}
+
+// Database pool for the server. There is none with STORAGE=memory, where
+// the Select*Repository providers keep everything in process memory.
+func SelectDB(cfg config.DBConfig) (*gorm.DB, func(), error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		log.Warnln("STORAGE=memory: nothing is stored in the database and everything is lost on restart")
+		return nil, func() {}, nil
+	case config.StorageDatabase:
+		return NewDB(cfg)
+	default:
+		return nil, nil, fmt.Errorf("unknown STORAGE %q: use %s or %s", cfg.Storage, config.StorageDatabase, config.StorageMemory)
+	}
+}
+
+func newDialector(cfg config.DBConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", "mysql":
+		return mysql.Open(cfg.DSN), nil
+	case "postgres":
+		return postgres.Open(cfg.DSN), nil
+	case "sqlite":
+		return sqlite.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+// Database handle bound to ctx, so queries stop when the request is cancelled.
+// Each call is also limited to timeout (DBConfig.QueryTimeout); zero disables it.
+func withContext(ctx context.Context, db *gorm.DB, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return db.WithContext(ctx), cancel
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return db.WithContext(ctx), cancel
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Readiness check that pings the database pool. Without one (STORAGE=memory)
+// there is nothing to wait for and the check always passes.
+type DBHealthChecker struct {
+	db *gorm.DB
+}
+
+func NewDBHealthChecker(db *gorm.DB) *DBHealthChecker {
+	checker := new(DBHealthChecker)
+	checker.db = db
+	return checker
+}
+
+func (d *DBHealthChecker) Name() string {
+	return "database"
+}
+
+func (d *DBHealthChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	if d.db == nil {
+		return map[string]interface{}{"storage": "memory"}, nil
+	}
+	db, err := d.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	stats := db.Stats()
+	details := map[string]interface{}{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"max_open":         stats.MaxOpenConnections,
+		"wait_count":       stats.WaitCount,
+		"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+	}
+	return details, db.PingContext(ctx)
+}
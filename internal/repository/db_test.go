@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestSelectDB(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// Memory storage never connects, so an unreachable database is fine
+	db, cleanup, err := SelectDB(config.DBConfig{Storage: config.StorageMemory, Driver: "mysql", DSN: "atmail:atmail@tcp(127.0.0.1:1)/atmail?timeout=1s"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(db).To(gomega.BeNil())
+	cleanup()
+
+	// Typos are refused rather than falling back to the database
+	_, _, err = SelectDB(config.DBConfig{Storage: "mem", Driver: "mysql", DSN: "atmail:atmail@tcp(127.0.0.1:1)/atmail?timeout=1s"})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown STORAGE "mem"`)))
+}
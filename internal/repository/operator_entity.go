@@ -0,0 +1,18 @@
+package repository
+
+import "time"
+
+type Operator struct {
+	ID                uint
+	Username          string
+	PasswordHash      string
+	Role              string
+	Disabled          bool
+	PasswordChangedAt time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (Operator) TableName() string {
+	return "operators"
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OperatorRepository kept in process memory, for running without a database.
+// Usernames are compared ignoring case like the MySQL collation does.
+type operatorMemoryRepository struct {
+	mu        sync.RWMutex
+	operators map[uint]Operator
+	nextID    uint
+}
+
+func NewOperatorMemoryRepository() OperatorRepository {
+	repo := new(operatorMemoryRepository)
+	repo.operators = make(map[uint]Operator)
+	repo.nextID = 1
+	return repo
+}
+
+func (o *operatorMemoryRepository) Count(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return int64(len(o.operators)), nil
+}
+
+func (o *operatorMemoryRepository) Get(ctx context.Context, id uint) (*Operator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	operator, ok := o.operators[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &operator, nil
+}
+
+func (o *operatorMemoryRepository) GetByUsername(ctx context.Context, username string) (*Operator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if operator, ok := o.byUsername(username); ok {
+		return &operator, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (o *operatorMemoryRepository) Save(ctx context.Context, operator Operator) (*Operator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.byUsername(operator.Username); ok {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	operator.ID = o.nextID
+	o.nextID++
+	if operator.CreatedAt.IsZero() {
+		operator.CreatedAt = time.Now()
+	}
+	operator.UpdatedAt = operator.CreatedAt
+	o.operators[operator.ID] = operator
+	return &operator, nil
+}
+
+func (o *operatorMemoryRepository) Update(ctx context.Context, operator Operator) (*Operator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	current, ok := o.operators[operator.ID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if other, ok := o.byUsername(operator.Username); ok && other.ID != operator.ID {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	operator.CreatedAt = current.CreatedAt
+	operator.UpdatedAt = time.Now()
+	o.operators[operator.ID] = operator
+	return &operator, nil
+}
+
+// Operator with username. Callers must hold the lock.
+func (o *operatorMemoryRepository) byUsername(username string) (Operator, bool) {
+	for _, operator := range o.operators {
+		if strings.EqualFold(operator.Username, username) {
+			return operator, true
+		}
+	}
+	return Operator{}, false
+}
+
+// Operator repository chosen by STORAGE: database (default) or memory
+func SelectOperatorRepository(db *gorm.DB, cfg config.DBConfig) OperatorRepository {
+	if cfg.InMemory() {
+		return NewOperatorMemoryRepository()
+	}
+	return NewOperatorRepository(db, cfg)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type operatorRepository struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+type OperatorRepository interface {
+	Count(ctx context.Context) (int64, error)
+	Get(ctx context.Context, id uint) (*Operator, error)
+	GetByUsername(ctx context.Context, username string) (*Operator, error)
+	Save(ctx context.Context, operator Operator) (*Operator, error)
+	Update(ctx context.Context, operator Operator) (*Operator, error)
+}
+
+func NewOperatorRepository(db *gorm.DB, cfg config.DBConfig) OperatorRepository {
+	repo := new(operatorRepository)
+	repo.db = db
+	repo.timeout = cfg.QueryTimeout
+	return repo
+}
+
+func (o *operatorRepository) Count(ctx context.Context) (int64, error) {
+	db, cancel := withContext(ctx, o.db, o.timeout)
+	defer cancel()
+	var count int64
+	if err := db.Model(&Operator{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (o *operatorRepository) Get(ctx context.Context, id uint) (*Operator, error) {
+	db, cancel := withContext(ctx, o.db, o.timeout)
+	defer cancel()
+	var operator Operator
+	operator.ID = id
+	if err := db.First(&operator).Error; err != nil {
+		return nil, err
+	}
+	return &operator, nil
+}
+
+func (o *operatorRepository) GetByUsername(ctx context.Context, username string) (*Operator, error) {
+	db, cancel := withContext(ctx, o.db, o.timeout)
+	defer cancel()
+	var operator Operator
+	if err := db.Where("username = ?", username).First(&operator).Error; err != nil {
+		return nil, err
+	}
+	return &operator, nil
+}
+
+func (o *operatorRepository) Save(ctx context.Context, operator Operator) (*Operator, error) {
+	db, cancel := withContext(ctx, o.db, o.timeout)
+	defer cancel()
+	if err := db.Create(&operator).Error; err != nil {
+		return nil, err
+	}
+	return &operator, nil
+}
+
+func (o *operatorRepository) Update(ctx context.Context, operator Operator) (*Operator, error) {
+	db, cancel := withContext(ctx, o.db, o.timeout)
+	defer cancel()
+	if err := db.Save(&operator).Error; err != nil {
+		return nil, err
+	}
+	return &operator, nil
+}
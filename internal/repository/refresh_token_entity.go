@@ -0,0 +1,27 @@
+package repository
+
+import "time"
+
+type RefreshToken struct {
+	ID         uint
+	OperatorID uint
+	TokenHash  string
+	// Shared by the tokens issued by rotating one login's refresh token
+	FamilyID  *string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// Family of the token. Tokens issued before families were recorded are the
+// family of their own hash.
+func (r RefreshToken) Family() string {
+	if r.FamilyID != nil {
+		return *r.FamilyID
+	}
+	return r.TokenHash
+}
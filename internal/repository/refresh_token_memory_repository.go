@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository kept in process memory, for running without a
+// database
+type refreshTokenMemoryRepository struct {
+	mu     sync.Mutex
+	tokens map[uint]RefreshToken
+	nextID uint
+}
+
+func NewRefreshTokenMemoryRepository() RefreshTokenRepository {
+	repo := new(refreshTokenMemoryRepository)
+	repo.tokens = make(map[uint]RefreshToken)
+	repo.nextID = 1
+	return repo
+}
+
+func (r *refreshTokenMemoryRepository) GetByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Revoke a token, reporting false when it was already revoked. Only one of
+// several concurrent calls for a token sees true.
+func (r *refreshTokenMemoryRepository) Revoke(ctx context.Context, id uint) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.RevokedAt != nil {
+		return false, nil
+	}
+	revokedAt := time.Now()
+	token.RevokedAt = &revokedAt
+	r.tokens[id] = token
+	return true, nil
+}
+
+// Revoke every token of a family
+func (r *refreshTokenMemoryRepository) RevokeFamily(ctx context.Context, family string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	revokedAt := time.Now()
+	for id, token := range r.tokens {
+		if token.Family() == family && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *refreshTokenMemoryRepository) Save(ctx context.Context, token RefreshToken) (*RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.tokens {
+		if other.TokenHash == token.TokenHash {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+	token.ID = r.nextID
+	r.nextID++
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.tokens[token.ID] = token
+	return &token, nil
+}
+
+// Refresh token repository chosen by STORAGE: database (default) or memory
+func SelectRefreshTokenRepository(db *gorm.DB, cfg config.DBConfig) RefreshTokenRepository {
+	if cfg.InMemory() {
+		return NewRefreshTokenMemoryRepository()
+	}
+	return NewRefreshTokenRepository(db, cfg)
+}
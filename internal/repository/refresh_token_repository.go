@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepository struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+type RefreshTokenRepository interface {
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	Revoke(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, family string) error
+	Save(ctx context.Context, token RefreshToken) (*RefreshToken, error)
+}
+
+func NewRefreshTokenRepository(db *gorm.DB, cfg config.DBConfig) RefreshTokenRepository {
+	repo := new(refreshTokenRepository)
+	repo.db = db
+	repo.timeout = cfg.QueryTimeout
+	return repo
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	db, cancel := withContext(ctx, r.db, r.timeout)
+	defer cancel()
+	var token RefreshToken
+	if err := db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Revoke a token, reporting false when it was already revoked. Only one of
+// several concurrent calls for a token sees true.
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id uint) (bool, error) {
+	db, cancel := withContext(ctx, r.db, r.timeout)
+	defer cancel()
+	result := db.Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Revoke every token of a family, including those from before families
+// were recorded whose hash is the family
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, family string) error {
+	db, cancel := withContext(ctx, r.db, r.timeout)
+	defer cancel()
+	return db.Model(&RefreshToken{}).
+		Where("(family_id = ? OR (family_id IS NULL AND token_hash = ?)) AND revoked_at IS NULL", family, family).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) Save(ctx context.Context, token RefreshToken) (*RefreshToken, error) {
+	db, cancel := withContext(ctx, r.db, r.timeout)
+	defer cancel()
+	if err := db.Create(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/migration"
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestRefreshTokenRepository_ConcurrentRevoke(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		ctx := context.Background()
+		cfg := config.DBConfig{Driver: "sqlite", DSN: config.SQLiteDSN(filepath.Join(t.TempDir(), "atmail.db")), MaxOpenConns: 4}
+		db, cleanup, err := NewDB(cfg)
+		g.Expect(err).To(gomega.BeNil())
+		t.Cleanup(cleanup)
+		migrator, err := migration.NewMigrator(db)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(migrator.Up(ctx)).To(gomega.Succeed())
+
+		operator, err := NewOperatorRepository(db, cfg).Save(ctx, Operator{Username: "admin", PasswordHash: "x", Role: "admin"})
+		g.Expect(err).To(gomega.BeNil())
+		testConcurrentRevoke(t, NewRefreshTokenRepository(db, cfg), operator.ID)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		testConcurrentRevoke(t, NewRefreshTokenMemoryRepository(), 1)
+	})
+}
+
+func testConcurrentRevoke(t *testing.T, repo RefreshTokenRepository, operatorID uint) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+	family := "family"
+	token, err := repo.Save(ctx, RefreshToken{OperatorID: operatorID, TokenHash: "first", FamilyID: &family, ExpiresAt: time.Now().Add(time.Hour)})
+	g.Expect(err).To(gomega.BeNil())
+
+	var wg sync.WaitGroup
+	var revoked atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.Revoke(ctx, token.ID)
+			g.Expect(err).To(gomega.BeNil())
+			if ok {
+				revoked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	g.Expect(revoked.Load()).To(gomega.Equal(int32(1)))
+
+	// Families take in tokens from before they were recorded by their hash
+	legacy, _ := repo.Save(ctx, RefreshToken{OperatorID: operatorID, TokenHash: "legacy", ExpiresAt: time.Now().Add(time.Hour)})
+	successor, _ := repo.Save(ctx, RefreshToken{OperatorID: operatorID, TokenHash: "second", FamilyID: &legacy.TokenHash, ExpiresAt: time.Now().Add(time.Hour)})
+	other, _ := repo.Save(ctx, RefreshToken{OperatorID: operatorID, TokenHash: "other", FamilyID: &family, ExpiresAt: time.Now().Add(time.Hour)})
+	g.Expect(repo.RevokeFamily(ctx, legacy.Family())).To(gomega.Succeed())
+	for _, hash := range []string{legacy.TokenHash, successor.TokenHash} {
+		got, _ := repo.GetByHash(ctx, hash)
+		g.Expect(got.RevokedAt).NotTo(gomega.BeNil())
+	}
+	got, _ := repo.GetByHash(ctx, other.TokenHash)
+	g.Expect(got.RevokedAt).To(gomega.BeNil())
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"atmail/internal/model"
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Append-only record of a change to a user
+type UserAudit struct {
+	ID        uint
+	UserID    uint
+	Action    string
+	Actor     *string
+	RequestID *string
+	SourceIP  *string
+	// JSON object of model.FieldChange by field name
+	Changes    string
+	OccurredAt time.Time
+}
+
+func (UserAudit) TableName() string {
+	return "user_audit"
+}
+
+// Audit entry for a change to a user made by the request in ctx
+func newUserAudit(ctx context.Context, userID uint, action string, changes map[string]model.FieldChange, at time.Time) (UserAudit, error) {
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return UserAudit{}, err
+	}
+	entry := UserAudit{UserID: userID, Action: action, Changes: string(encoded), OccurredAt: at}
+	if principal := model.PrincipalFromContext(ctx); principal != nil {
+		actor := principal.Ref()
+		entry.Actor = &actor
+	}
+	meta := model.RequestMetaFromContext(ctx)
+	entry.RequestID = optionalString(meta.RequestID)
+	entry.SourceIP = optionalString(meta.SourceIP)
+	return entry, nil
+}
+
+// Write the audit entry of a change in the transaction making it
+func recordUserAudit(ctx context.Context, tx *gorm.DB, userID uint, action string, changes map[string]model.FieldChange, at time.Time) error {
+	entry, err := newUserAudit(ctx, userID, action, changes, at)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&entry).Error
+}
+
+// Fields that differ between two states of a user. A nil state has no
+// values, so creating or purging a user lists every field.
+func userChanges(before, after *User) map[string]model.FieldChange {
+	fields := []struct {
+		name  string
+		value func(User) interface{}
+	}{
+		{"username", func(user User) interface{} { return user.Username }},
+		{"email", func(user User) interface{} { return user.Email }},
+		{"age", func(user User) interface{} { return user.Age }},
+	}
+	changes := map[string]model.FieldChange{}
+	for _, field := range fields {
+		var b, a interface{}
+		if before != nil {
+			b = field.value(*before)
+		}
+		if after != nil {
+			a = field.value(*after)
+		}
+		if b != a {
+			changes[field.name] = model.FieldChange{Before: b, After: a}
+		}
+	}
+	return changes
+}
+
+// Change of deleted_at when a user is deleted or restored
+func deletionChange(before, after *time.Time) map[string]model.FieldChange {
+	change := model.FieldChange{}
+	if before != nil {
+		change.Before = before.UTC()
+	}
+	if after != nil {
+		change.After = after.UTC()
+	}
+	return map[string]model.FieldChange{"deleted_at": change}
+}
+
+func toUserAuditModel(entry UserAudit) (model.UserAuditEntry, error) {
+	m := model.UserAuditEntry{
+		ID:         entry.ID,
+		UserID:     entry.UserID,
+		Action:     entry.Action,
+		Actor:      stringValue(entry.Actor),
+		RequestID:  stringValue(entry.RequestID),
+		SourceIP:   stringValue(entry.SourceIP),
+		OccurredAt: entry.OccurredAt.UTC(),
+	}
+	if err := json.Unmarshal([]byte(entry.Changes), &m.Changes); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filtering and keyset pagination options for listing audit entries, which
+// come newest first
+type UserAuditListOptions struct {
+	Limit     int
+	BeforeID  uint
+	UserID    *uint
+	Action    string
+	Actor     string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+}
+
+// Read access to the user audit log. Entries are only ever written by the
+// UserRepository, in the transaction making the change.
+type UserAuditRepository interface {
+	List(ctx context.Context, opts UserAuditListOptions) (*[]model.UserAuditEntry, error)
+}
+
+type userAuditRepository struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+func NewUserAuditRepository(db *gorm.DB, cfg config.DBConfig) UserAuditRepository {
+	repo := new(userAuditRepository)
+	repo.db = db
+	repo.timeout = cfg.QueryTimeout
+	return repo
+}
+
+func (u *userAuditRepository) List(ctx context.Context, opts UserAuditListOptions) (*[]model.UserAuditEntry, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	query := db.Model(&UserAudit{})
+	if opts.BeforeID != 0 {
+		query = query.Where("id < ?", opts.BeforeID)
+	}
+	if opts.UserID != nil {
+		query = query.Where("user_id = ?", *opts.UserID)
+	}
+	if opts.Action != "" {
+		query = query.Where("action = ?", opts.Action)
+	}
+	if opts.Actor != "" {
+		query = query.Where("actor = ?", opts.Actor)
+	}
+	if opts.RequestID != "" {
+		query = query.Where("request_id = ?", opts.RequestID)
+	}
+	if opts.From != nil {
+		query = query.Where("occurred_at >= ?", opts.From.UTC())
+	}
+	if opts.To != nil {
+		query = query.Where("occurred_at < ?", opts.To.UTC())
+	}
+	var entries []UserAudit
+	if err := query.Order("id DESC").Limit(opts.Limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return toUserAuditModels(entries)
+}
+
+// UserAuditRepository reading the log kept by a userMemoryRepository
+type userAuditMemoryRepository struct {
+	users *userMemoryRepository
+}
+
+func (u *userAuditMemoryRepository) List(ctx context.Context, opts UserAuditListOptions) (*[]model.UserAuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.users.mu.RLock()
+	defer u.users.mu.RUnlock()
+	var entries []UserAudit
+	for i := len(u.users.audit) - 1; i >= 0 && len(entries) < opts.Limit; i-- {
+		if entry := u.users.audit[i]; matchesAuditFilters(entry, opts) {
+			entries = append(entries, entry)
+		}
+	}
+	return toUserAuditModels(entries)
+}
+
+func matchesAuditFilters(entry UserAudit, opts UserAuditListOptions) bool {
+	switch {
+	case opts.BeforeID != 0 && entry.ID >= opts.BeforeID:
+		return false
+	case opts.UserID != nil && entry.UserID != *opts.UserID:
+		return false
+	case opts.Action != "" && entry.Action != opts.Action:
+		return false
+	case opts.Actor != "" && stringValue(entry.Actor) != opts.Actor:
+		return false
+	case opts.RequestID != "" && stringValue(entry.RequestID) != opts.RequestID:
+		return false
+	case opts.From != nil && entry.OccurredAt.Before(*opts.From):
+		return false
+	case opts.To != nil && !entry.OccurredAt.Before(*opts.To):
+		return false
+	}
+	return true
+}
+
+func toUserAuditModels(entries []UserAudit) (*[]model.UserAuditEntry, error) {
+	m := make([]model.UserAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		e, err := toUserAuditModel(entry)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, e)
+	}
+	return &m, nil
+}
+
+// Audit repository matching the storage SelectUserRepository chose, so the
+// memory store serves its own log
+func SelectUserAuditRepository(db *gorm.DB, cfg config.DBConfig, users UserRepository) UserAuditRepository {
+	if memory, ok := users.(*userMemoryRepository); ok {
+		return &userAuditMemoryRepository{users: memory}
+	}
+	return NewUserAuditRepository(db, cfg)
+}
@@ -1,12 +1,68 @@
 package repository
 
+import (
+	"atmail/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
 	ID       uint
 	Username string
 	Email    string
 	Age      int
+	// Starts at 1 and is incremented by every Update
+	Version   uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Principals that made the changes, nil when unknown
+	CreatedBy *string
+	UpdatedBy *string
+	// Set while the user is soft deleted. GORM leaves deleted users out of
+	// queries unless they are Unscoped.
+	DeletedAt gorm.DeletedAt
 }
 
 func (User) TableName() string {
 	return "users"
 }
+
+// Timestamps are returned in UTC whatever the driver's location
+func toUserModel(user User) model.User {
+	return model.User{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Age:       user.Age,
+		Version:   user.Version,
+		CreatedAt: user.CreatedAt.UTC(),
+		UpdatedAt: user.UpdatedAt.UTC(),
+		CreatedBy: stringValue(user.CreatedBy),
+		UpdatedBy: stringValue(user.UpdatedBy),
+		DeletedAt: deletedAt(user.DeletedAt),
+	}
+}
+
+func toUserModels(users []User) []model.User {
+	m := make([]model.User, len(users))
+	for i, user := range users {
+		m[i] = toUserModel(user)
+	}
+	return m
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	t := d.Time.UTC()
+	return &t
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Returned by Save and Update when another user already has the email or
+// username. The unique indexes catch what IsEmailUnique and IsUsernameUnique
+// cannot: two requests saving the same value at once.
+type ConflictError struct {
+	// email or username
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Field + " already exists"
+}
+
+// Returned by Update and Delete when the user's version is not the one
+// expected, because another request changed it in the meantime
+var ErrVersionMismatch = errors.New("user version does not match")
+
+// Returned by Restore when the user is not deleted
+var ErrNotDeleted = errors.New("user is not deleted")
+
+const (
+	mysqlDuplicateEntry     = 1062
+	postgresUniqueViolation = "23505"
+	sqliteConstraintUnique  = 2067
+)
+
+// Convert a duplicate key error on the users table into a ConflictError
+func translateUserError(err error) error {
+	key, ok := duplicateKey(err)
+	if !ok {
+		return err
+	}
+	switch {
+	case strings.Contains(key, "email"):
+		return &ConflictError{Field: "email"}
+	case strings.Contains(key, "username"):
+		return &ConflictError{Field: "username"}
+	default:
+		return err
+	}
+}
+
+// Name of the index or column a duplicate key error refers to, as reported
+// by each driver
+func duplicateKey(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		// Duplicate entry '<value>' for key '<table>.<index>'
+		return mysqlErr.Message[strings.LastIndex(mysqlErr.Message, " for key ")+1:], true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == postgresUniqueViolation {
+		return pgErr.ConstraintName, true
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteConstraintUnique {
+		return sqliteErr.Error(), true
+	}
+	return "", false
+}
+
+// Rolls back a write transaction that turned out to change nothing, so the
+// change marker is not bumped. Never returned to callers.
+var errUnchanged = errors.New("nothing changed")
@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"atmail/internal/model"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository kept in process memory, for tests and running without a
+// database. String comparisons ignore case like the MySQL collation does.
+type userMemoryRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]User
+	nextID uint
+	marker model.ChangeMarker
+	audit  []UserAudit
+}
+
+func NewUserMemoryRepository() UserRepository {
+	repo := new(userMemoryRepository)
+	repo.users = make(map[uint]User)
+	repo.nextID = 1
+	repo.marker = model.ChangeMarker{Version: 1, ChangedAt: now()}
+	return repo
+}
+
+func (u *userMemoryRepository) Get(ctx context.Context, id uint) (*model.User, error) {
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m := toUserModel(*user)
+	return &m, nil
+}
+
+func (u *userMemoryRepository) GetAll(ctx context.Context) (*[]model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	m := []model.User{}
+	for _, user := range u.sorted(func(a, b User) bool { return a.ID < b.ID }) {
+		if !user.DeletedAt.Valid {
+			m = append(m, toUserModel(user))
+		}
+	}
+	return &m, nil
+}
+
+func (u *userMemoryRepository) List(ctx context.Context, opts UserListOptions) (*[]model.User, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	var matched []User
+	for _, user := range u.users {
+		if matchesListFilters(user, opts) {
+			matched = append(matched, user)
+		}
+	}
+	total := int64(len(matched))
+
+	sort.Slice(matched, func(i, j int) bool {
+		c := compareUsers(matched[i], matched[j], opts.SortField)
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	m := []model.User{}
+	for _, user := range matched {
+		if opts.AfterID != 0 && !isAfterCursor(user, opts) {
+			continue
+		}
+		if len(m) == opts.Limit {
+			break
+		}
+		m = append(m, toUserModel(user))
+	}
+	return &m, total, nil
+}
+
+func (u *userMemoryRepository) GetUser(ctx context.Context, id uint) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	user, ok := u.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (u *userMemoryRepository) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
+	return u.isUnique(ctx, id, func(user User) string { return user.Email }, email, includeDeleted)
+}
+
+func (u *userMemoryRepository) IsUsernameUnique(ctx context.Context, id *uint, username string, includeDeleted bool) (bool, error) {
+	return u.isUnique(ctx, id, func(user User) string { return user.Username }, username, includeDeleted)
+}
+
+func (u *userMemoryRepository) Save(ctx context.Context, user User) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if user.ID == 0 {
+		user.ID = u.nextID
+	} else if _, exists := u.users[user.ID]; exists {
+		return nil, fmt.Errorf("duplicate primary key %d", user.ID)
+	}
+	if err := u.checkConflicts(user); err != nil {
+		return nil, err
+	}
+	if user.ID >= u.nextID {
+		u.nextID = user.ID + 1
+	}
+	user.Version = 1
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	entry, err := newUserAudit(ctx, user.ID, model.AuditCreate, userChanges(nil, &user), user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.touch(user.UpdatedAt)
+	u.users[user.ID] = user
+	u.record(entry)
+	m := toUserModel(user)
+	return &m, nil
+}
+
+// Update a user if it still has user.Version, incrementing the version
+func (u *userMemoryRepository) Update(ctx context.Context, user User) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	current, ok := u.users[user.ID]
+	if !ok || current.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if current.Version != user.Version {
+		return nil, ErrVersionMismatch
+	}
+	user.DeletedAt = current.DeletedAt
+	if err := u.checkConflicts(user); err != nil {
+		return nil, err
+	}
+	user.Version++
+	user.CreatedAt = current.CreatedAt
+	user.CreatedBy = current.CreatedBy
+	user.UpdatedAt = now()
+	entry, err := newUserAudit(ctx, user.ID, model.AuditUpdate, userChanges(&current, &user), user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.touch(user.UpdatedAt)
+	u.users[user.ID] = user
+	u.record(entry)
+	m := toUserModel(user)
+	return &m, nil
+}
+
+// Soft delete a user, only if it has version when one is given, and
+// increment its version. Deleting a missing or deleted user is not an error.
+func (u *userMemoryRepository) Delete(ctx context.Context, id uint, version *uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	current, ok := u.users[id]
+	if !ok || current.DeletedAt.Valid {
+		return nil
+	}
+	if version != nil && current.Version != *version {
+		return ErrVersionMismatch
+	}
+	deletedAt := now()
+	entry, err := newUserAudit(ctx, id, model.AuditDelete, deletionChange(nil, &deletedAt), deletedAt)
+	if err != nil {
+		return err
+	}
+	u.touch(deletedAt)
+	current.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+	current.Version++
+	u.users[id] = current
+	u.record(entry)
+	return nil
+}
+
+// Undo the soft deletion of a user, only if it has version when one is
+// given, and increment its version
+func (u *userMemoryRepository) Restore(ctx context.Context, id uint, version *uint, restoredBy *string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	current, ok := u.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if !current.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+	if version != nil && current.Version != *version {
+		return nil, ErrVersionMismatch
+	}
+	deletedAt := current.DeletedAt.Time
+	current.DeletedAt = gorm.DeletedAt{}
+	if err := u.checkConflicts(current); err != nil {
+		return nil, err
+	}
+	current.Version++
+	current.UpdatedAt = now()
+	current.UpdatedBy = restoredBy
+	entry, err := newUserAudit(ctx, id, model.AuditRestore, deletionChange(&deletedAt, nil), current.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.touch(current.UpdatedAt)
+	u.users[id] = current
+	u.record(entry)
+	m := toUserModel(current)
+	return &m, nil
+}
+
+// Permanently remove a user, deleted or not, only if it has version when
+// one is given
+func (u *userMemoryRepository) Purge(ctx context.Context, id uint, version *uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	current, ok := u.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if version != nil && current.Version != *version {
+		return ErrVersionMismatch
+	}
+	purgedAt := now()
+	entry, err := newUserAudit(ctx, id, model.AuditPurge, userChanges(&current, nil), purgedAt)
+	if err != nil {
+		return err
+	}
+	u.touch(purgedAt)
+	delete(u.users, id)
+	u.record(entry)
+	return nil
+}
+
+func (u *userMemoryRepository) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	marker := u.marker
+	return &marker, nil
+}
+
+// Append an audit entry. Callers must hold the lock.
+func (u *userMemoryRepository) record(entry UserAudit) {
+	entry.ID = uint(len(u.audit)) + 1
+	u.audit = append(u.audit, entry)
+}
+
+// Bump the change marker. Callers must hold the lock.
+func (u *userMemoryRepository) touch(changedAt time.Time) {
+	u.marker.Version++
+	u.marker.ChangedAt = changedAt
+}
+
+func (u *userMemoryRepository) isUnique(ctx context.Context, id *uint, field func(User) string, value string, includeDeleted bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, user := range u.users {
+		if (id != nil && user.ID == *id) || (user.DeletedAt.Valid && !includeDeleted) {
+			continue
+		}
+		if strings.EqualFold(field(user), value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Enforce the unique email and username indexes of the database, which
+// leave out deleted users. Callers must hold the lock.
+func (u *userMemoryRepository) checkConflicts(user User) error {
+	for _, other := range u.users {
+		if other.ID == user.ID || other.DeletedAt.Valid {
+			continue
+		}
+		if strings.EqualFold(other.Email, user.Email) {
+			return &ConflictError{Field: "email"}
+		}
+		if strings.EqualFold(other.Username, user.Username) {
+			return &ConflictError{Field: "username"}
+		}
+	}
+	return nil
+}
+
+// Callers must hold the lock
+func (u *userMemoryRepository) sorted(less func(a, b User) bool) []User {
+	users := make([]User, 0, len(u.users))
+	for _, user := range u.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return less(users[i], users[j]) })
+	return users
+}
+
+func matchesListFilters(user User, opts UserListOptions) bool {
+	if user.DeletedAt.Valid && !opts.IncludeDeleted {
+		return false
+	}
+	if opts.UsernamePrefix != "" && !strings.HasPrefix(strings.ToLower(user.Username), strings.ToLower(opts.UsernamePrefix)) {
+		return false
+	}
+	if opts.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(user.Email), "@"+strings.ToLower(opts.EmailDomain)) {
+		return false
+	}
+	if opts.MinAge != nil && user.Age < *opts.MinAge {
+		return false
+	}
+	if opts.MaxAge != nil && user.Age > *opts.MaxAge {
+		return false
+	}
+	if opts.CreatedAfter != nil && !user.CreatedAt.After(*opts.CreatedAfter) {
+		return false
+	}
+	if opts.UpdatedBefore != nil && !user.UpdatedAt.Before(*opts.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+// Order users by field, then by ID
+func compareUsers(a, b User, field string) int {
+	if c := compareField(a, field, sortField(b, field)); c != 0 {
+		return c
+	}
+	return compareIDs(a.ID, b.ID)
+}
+
+// Whether user comes after the cursor row in the requested order
+func isAfterCursor(user User, opts UserListOptions) bool {
+	c := compareIDs(user.ID, opts.AfterID)
+	if opts.SortField != "id" {
+		if fc := compareField(user, opts.SortField, opts.AfterValue); fc != 0 {
+			c = fc
+		}
+	}
+	if opts.Descending {
+		return c < 0
+	}
+	return c > 0
+}
+
+func sortField(user User, field string) interface{} {
+	switch field {
+	case "username":
+		return user.Username
+	case "email":
+		return user.Email
+	case "age":
+		return user.Age
+	default:
+		return user.ID
+	}
+}
+
+// Compare a field of user against value, which may come from a decoded
+// cursor where numbers are float64
+func compareField(user User, field string, value interface{}) int {
+	switch field {
+	case "username", "email":
+		return strings.Compare(strings.ToLower(sortField(user, field).(string)), strings.ToLower(fmt.Sprint(value)))
+	case "age":
+		var other float64
+		switch v := value.(type) {
+		case int:
+			other = float64(v)
+		case float64:
+			other = v
+		}
+		age := float64(user.Age)
+		if age < other {
+			return -1
+		} else if age > other {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+func compareIDs(a, b uint) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -2,65 +2,172 @@ package repository
 
 import (
 	"atmail/internal/config"
+	"atmail/internal/helper"
 	"atmail/internal/model"
+	"context"
 	"errors"
+	"strings"
+	"time"
 
-	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
 )
 
 type userRepository struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+// Filtering, sorting and keyset pagination options for List
+type UserListOptions struct {
+	Limit          int
+	SortField      string
+	Descending     bool
+	AfterValue     interface{}
+	AfterID        uint
+	UsernamePrefix string
+	EmailDomain    string
+	MinAge         *int
+	MaxAge         *int
+	CreatedAfter   *time.Time
+	UpdatedBefore  *time.Time
+	IncludeDeleted bool
 }
 
 type UserRepository interface {
-	Delete(id uint) error
-	Get(id uint) (*model.User, error)
-	GetAll() (*[]model.User, error)
-	GetUser(id uint) (*User, error)
-	IsEmailUnique(id *uint, email string) (bool, error)
-	IsUsernameUnique(id *uint, email string) (bool, error)
-	Save(user User) (*model.User, error)
-	Update(user User) (*model.User, error)
+	Delete(ctx context.Context, id uint, version *uint) error
+	Get(ctx context.Context, id uint) (*model.User, error)
+	GetAll(ctx context.Context) (*[]model.User, error)
+	List(ctx context.Context, opts UserListOptions) (*[]model.User, int64, error)
+	GetUser(ctx context.Context, id uint) (*User, error)
+	IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error)
+	IsUsernameUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error)
+	Save(ctx context.Context, user User) (*model.User, error)
+	Update(ctx context.Context, user User) (*model.User, error)
+	Restore(ctx context.Context, id uint, version *uint, restoredBy *string) (*model.User, error)
+	Purge(ctx context.Context, id uint, version *uint) error
+	ChangeMarker(ctx context.Context) (*model.ChangeMarker, error)
 }
 
-func NewUserRepository() UserRepository {
+func NewUserRepository(db *gorm.DB, cfg config.DBConfig) UserRepository {
 	repo := new(userRepository)
+	repo.db = db
+	repo.timeout = cfg.QueryTimeout
 	return repo
 }
 
-func (u *userRepository) Get(id uint) (*model.User, error) {
+func (u *userRepository) Get(ctx context.Context, id uint) (*model.User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
 	var user User
 	user.ID = id
-	if err := config.DB().Take(&user).Error; err != nil {
+	if err := db.Take(&user).Error; err != nil {
 		return nil, err
 	}
-	var m model.User
-	copier.Copy(&m, user)
+	m := toUserModel(user)
 	return &m, nil
 }
 
-func (u *userRepository) GetAll() (*[]model.User, error) {
+func (u *userRepository) GetAll(ctx context.Context) (*[]model.User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
 	var users []User
-	if err := config.DB().Find(&users).Error; err != nil {
+	if err := db.Find(&users).Error; err != nil {
 		return nil, err
 	}
-	var m []model.User
-	copier.Copy(&m, users)
+	m := toUserModels(users)
 	return &m, nil
 }
 
-func (u *userRepository) Save(user User) (*model.User, error) {
-	if err := config.DB().Create(&user).Error; err != nil {
-		return nil, err
+func (u *userRepository) List(ctx context.Context, opts UserListOptions) (*[]model.User, int64, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	query := db.Model(&User{})
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.UsernamePrefix != "" {
+		query = query.Where(caseless(db, "username")+" LIKE ? ESCAPE '"+helper.LikeEscape+"'", helper.EscapeLike(strings.ToLower(opts.UsernamePrefix))+"%")
+	}
+	if opts.EmailDomain != "" {
+		query = query.Where(caseless(db, "email")+" LIKE ? ESCAPE '"+helper.LikeEscape+"'", "%@"+helper.EscapeLike(strings.ToLower(opts.EmailDomain)))
+	}
+	if opts.MinAge != nil {
+		query = query.Where("age >= ?", *opts.MinAge)
+	}
+	if opts.MaxAge != nil {
+		query = query.Where("age <= ?", *opts.MaxAge)
+	}
+	if opts.CreatedAfter != nil {
+		query = query.Where("created_at > ?", opts.CreatedAfter.UTC())
+	}
+	if opts.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", opts.UpdatedBefore.UTC())
 	}
-	var m model.User
-	copier.Copy(&m, user)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	direction, comparator := "ASC", ">"
+	if opts.Descending {
+		direction, comparator = "DESC", "<"
+	}
+	if opts.AfterID != 0 {
+		if opts.SortField == "id" {
+			query = query.Where("id "+comparator+" ?", opts.AfterID)
+		} else {
+			query = query.Where(
+				opts.SortField+" "+comparator+" ? OR ("+opts.SortField+" = ? AND id "+comparator+" ?)",
+				opts.AfterValue, opts.AfterValue, opts.AfterID,
+			)
+		}
+	}
+	if opts.SortField != "id" {
+		query = query.Order(opts.SortField + " " + direction)
+	}
+	query = query.Order("id " + direction)
+
+	var users []User
+	if err := query.Limit(opts.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	m := toUserModels(users)
+	return &m, total, nil
+}
+
+func (u *userRepository) Save(ctx context.Context, user User) (*model.User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	user.Version = 1
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := touchChangeMarker(tx, user.TableName(), user.UpdatedAt); err != nil {
+			return err
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordUserAudit(ctx, tx, user.ID, model.AuditCreate, userChanges(nil, &user), user.CreatedAt)
+	})
+	if err != nil {
+		return nil, translateUserError(err)
+	}
+	m := toUserModel(user)
 	return &m, nil
 }
 
-func (u *userRepository) IsEmailUnique(id *uint, email string) (bool, error) {
+// Whether no other user has email. Deleted users count when includeDeleted
+// is set.
+func (u *userRepository) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	if includeDeleted {
+		db = db.Unscoped()
+	}
 	var user User
-	query := config.DB().Where("email = ?", email)
+	query := db.Where(caseless(db, "email")+" = ?", strings.ToLower(email))
 	if id != nil {
 		query = query.Where("id != ?", *id)
 	}
@@ -74,8 +181,15 @@ func (u *userRepository) IsEmailUnique(id *uint, email string) (bool, error) {
 	return false, nil
 }
 
-func (u *userRepository) IsUsernameUnique(id *uint, username string) (bool, error) {
-	query := config.DB().Where("username = ?", username)
+// Whether no other user has username. Deleted users count when
+// includeDeleted is set.
+func (u *userRepository) IsUsernameUnique(ctx context.Context, id *uint, username string, includeDeleted bool) (bool, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	if includeDeleted {
+		db = db.Unscoped()
+	}
+	query := db.Where(caseless(db, "username")+" = ?", strings.ToLower(username))
 	if id != nil {
 		query = query.Where("id != ?", *id)
 	}
@@ -88,29 +202,202 @@ func (u *userRepository) IsUsernameUnique(id *uint, username string) (bool, erro
 	return false, nil
 }
 
-func (u *userRepository) GetUser(id uint) (*User, error) {
+func (u *userRepository) GetUser(ctx context.Context, id uint) (*User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
 	var user User
 	user.ID = id
-	if err := config.DB().First(&user).Error; err != nil {
+	if err := db.First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (u *userRepository) Update(user User) (*model.User, error) {
-	if err := config.DB().Save(&user).Error; err != nil {
-		return nil, err
+// Update a user if it still has user.Version, incrementing the version
+func (u *userRepository) Update(ctx context.Context, user User) (*model.User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	user.UpdatedAt = now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := touchChangeMarker(tx, user.TableName(), user.UpdatedAt); err != nil {
+			return err
+		}
+		// The marker's lock keeps other writers out until the change is
+		// audited, so this is the state being replaced
+		var before User
+		if err := tx.Take(&before, user.ID).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&User{}).
+			Where("id = ? AND version = ?", user.ID, user.Version).
+			Updates(map[string]interface{}{
+				"username":   user.Username,
+				"email":      user.Email,
+				"age":        user.Age,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": user.UpdatedAt,
+				"updated_by": user.UpdatedBy,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return u.missingOrChanged(tx, user.ID)
+		}
+		return recordUserAudit(ctx, tx, user.ID, model.AuditUpdate, userChanges(&before, &user), user.UpdatedAt)
+	})
+	if err != nil {
+		return nil, translateUserError(err)
 	}
-	var m model.User
-	copier.Copy(&m, user)
+	user.Version++
+	m := toUserModel(user)
 	return &m, nil
 }
 
-func (u *userRepository) Delete(id uint) error {
+// Soft delete a user, only if it has version when one is given, and
+// increment its version. Deleting a missing or deleted user is not an error.
+func (u *userRepository) Delete(ctx context.Context, id uint, version *uint) error {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	deletedAt := now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := touchChangeMarker(tx, User{}.TableName(), deletedAt); err != nil {
+			return err
+		}
+		query := tx.Model(&User{}).Where("id = ?", id)
+		if version != nil {
+			query = query.Where("version = ?", *version)
+		}
+		result := query.Updates(map[string]interface{}{
+			"deleted_at": deletedAt,
+			"version":    gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if version == nil {
+				return errUnchanged
+			}
+			if err := u.missingOrChanged(tx, id); !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			return errUnchanged
+		}
+		return recordUserAudit(ctx, tx, id, model.AuditDelete, deletionChange(nil, &deletedAt), deletedAt)
+	})
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	return err
+}
+
+// Undo the soft deletion of a user, only if it has version when one is
+// given, and increment its version
+func (u *userRepository) Restore(ctx context.Context, id uint, version *uint, restoredBy *string) (*model.User, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
 	var user User
-	user.ID = id
-	if err := config.DB().Delete(&user).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		restoredAt := now()
+		if err := touchChangeMarker(tx, User{}.TableName(), restoredAt); err != nil {
+			return err
+		}
+		var before User
+		if err := tx.Unscoped().Take(&before, id).Error; err != nil {
+			return err
+		}
+		if !before.DeletedAt.Valid {
+			return ErrNotDeleted
+		}
+		if version != nil && before.Version != *version {
+			return ErrVersionMismatch
+		}
+		err := tx.Unscoped().Model(&User{}).
+			Where("id = ? AND version = ?", id, before.Version).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": restoredAt,
+				"updated_by": restoredBy,
+			}).Error
+		if err != nil {
+			return err
+		}
+		if err := recordUserAudit(ctx, tx, id, model.AuditRestore, deletionChange(&before.DeletedAt.Time, nil), restoredAt); err != nil {
+			return err
+		}
+		return tx.Take(&user, id).Error
+	})
+	if err != nil {
+		return nil, translateUserError(err)
+	}
+	m := toUserModel(user)
+	return &m, nil
+}
+
+// Permanently remove a user, deleted or not, only if it has version when
+// one is given
+func (u *userRepository) Purge(ctx context.Context, id uint, version *uint) error {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		purgedAt := now()
+		if err := touchChangeMarker(tx, User{}.TableName(), purgedAt); err != nil {
+			return err
+		}
+		var before User
+		if err := tx.Unscoped().Take(&before, id).Error; err != nil {
+			return err
+		}
+		if version != nil && before.Version != *version {
+			return ErrVersionMismatch
+		}
+		if err := tx.Unscoped().Delete(&User{}, id).Error; err != nil {
+			return err
+		}
+		return recordUserAudit(ctx, tx, id, model.AuditPurge, userChanges(&before, nil), purgedAt)
+	})
+}
+
+// Version and time of the latest change to any user
+func (u *userRepository) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	db, cancel := withContext(ctx, u.db, u.timeout)
+	defer cancel()
+	var marker ChangeMarker
+	if err := db.Where("table_name = ?", User{}.TableName()).Take(&marker).Error; err != nil {
+		return nil, err
+	}
+	return &model.ChangeMarker{Version: marker.Version, ChangedAt: marker.ChangedAt.UTC()}, nil
+}
+
+// Why a conditional write matched no row: gorm.ErrRecordNotFound when the
+// user does not exist, otherwise ErrVersionMismatch
+func (u *userRepository) missingOrChanged(db *gorm.DB, id uint) error {
+	var count int64
+	if err := db.Model(&User{}).Where("id = ?", id).Count(&count).Error; err != nil {
 		return err
 	}
-	return nil
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrVersionMismatch
+}
+
+// User repository chosen by STORAGE: database (default) or memory
+func SelectUserRepository(db *gorm.DB, cfg config.DBConfig) UserRepository {
+	if cfg.InMemory() {
+		return NewUserMemoryRepository()
+	}
+	return NewUserRepository(db, cfg)
+}
+
+// Column for comparisons that ignore case, against lower case values, as the
+// unique indexes do. MySQL's collation already ignores case, and comparing
+// the bare column there keeps its indexes usable.
+func caseless(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "mysql" {
+		return column
+	}
+	return "lower(" + column + ")"
 }
@@ -0,0 +1,570 @@
+package repository
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/migration"
+	"atmail/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+// Behaviour every UserRepository implementation must share. newRepository
+// returns an empty repository.
+func testUserRepositoryContract(t *testing.T, newRepository func(t *testing.T) UserRepository) {
+	ctx := context.Background()
+
+	t.Run("Save and Get", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		saved, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(saved.ID).NotTo(gomega.BeZero())
+
+		got, err := repo.Get(ctx, saved.ID)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(*got).To(gomega.Equal(*saved))
+
+		entity, err := repo.GetUser(ctx, saved.ID)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(entity.Username).To(gomega.Equal("alice"))
+	})
+
+	t.Run("Missing users are gorm.ErrRecordNotFound", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		_, err := repo.Get(ctx, 404)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		_, err = repo.GetUser(ctx, 404)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+	})
+
+	t.Run("Uniqueness", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		saved, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+
+		unique, err := repo.IsEmailUnique(ctx, nil, "alice@example.com", true)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(unique).To(gomega.BeFalse())
+		unique, _ = repo.IsEmailUnique(ctx, &saved.ID, "alice@example.com", true)
+		g.Expect(unique).To(gomega.BeTrue())
+		unique, _ = repo.IsEmailUnique(ctx, nil, "bob@example.com", true)
+		g.Expect(unique).To(gomega.BeTrue())
+
+		unique, err = repo.IsUsernameUnique(ctx, nil, "alice", true)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(unique).To(gomega.BeFalse())
+		unique, _ = repo.IsUsernameUnique(ctx, &saved.ID, "alice", true)
+		g.Expect(unique).To(gomega.BeTrue())
+		unique, _ = repo.IsUsernameUnique(ctx, nil, "bob", true)
+		g.Expect(unique).To(gomega.BeTrue())
+
+		// Case does not make values different
+		unique, _ = repo.IsEmailUnique(ctx, nil, "Alice@Example.com", true)
+		g.Expect(unique).To(gomega.BeFalse())
+		unique, _ = repo.IsUsernameUnique(ctx, nil, "ALICE", true)
+		g.Expect(unique).To(gomega.BeFalse())
+	})
+
+	t.Run("Update and Delete", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		saved, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+
+		entity, _ := repo.GetUser(ctx, saved.ID)
+		entity.Age = 31
+		updated, err := repo.Update(ctx, *entity)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(updated.Age).To(gomega.Equal(31))
+		got, _ := repo.Get(ctx, saved.ID)
+		g.Expect(got.Age).To(gomega.Equal(31))
+
+		g.Expect(repo.Delete(ctx, saved.ID, nil)).To(gomega.Succeed())
+		_, err = repo.Get(ctx, saved.ID)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		g.Expect(repo.Delete(ctx, saved.ID, nil)).To(gomega.Succeed())
+	})
+
+	t.Run("Updates and deletes are conditional on the version", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		saved, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(saved.Version).To(gomega.Equal(uint(1)))
+
+		entity, _ := repo.GetUser(ctx, saved.ID)
+		stale := *entity
+		entity.Age = 31
+		updated, err := repo.Update(ctx, *entity)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(updated.Version).To(gomega.Equal(uint(2)))
+		got, _ := repo.Get(ctx, saved.ID)
+		g.Expect(*got).To(gomega.Equal(*updated))
+
+		stale.Age = 40
+		_, err = repo.Update(ctx, stale)
+		g.Expect(errors.Is(err, ErrVersionMismatch)).To(gomega.BeTrue())
+		got, _ = repo.Get(ctx, saved.ID)
+		g.Expect(got.Age).To(gomega.Equal(31))
+		_, err = repo.Update(ctx, User{ID: 404, Username: "bob", Email: "bob@example.com", Age: 30, Version: 1})
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+
+		stale.Version = 1
+		g.Expect(errors.Is(repo.Delete(ctx, saved.ID, &stale.Version), ErrVersionMismatch)).To(gomega.BeTrue())
+		g.Expect(repo.Delete(ctx, saved.ID, &updated.Version)).To(gomega.Succeed())
+		_, err = repo.Get(ctx, saved.ID)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		g.Expect(repo.Delete(ctx, saved.ID, &updated.Version)).To(gomega.Succeed())
+	})
+
+	t.Run("Deleted users can be restored until purged", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		alice, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		_, _ = repo.Save(ctx, User{Username: "bob", Email: "bob@example.com", Age: 40})
+		_, err := repo.Restore(ctx, alice.ID, nil, nil)
+		g.Expect(errors.Is(err, ErrNotDeleted)).To(gomega.BeTrue())
+
+		g.Expect(repo.Delete(ctx, alice.ID, &alice.Version)).To(gomega.Succeed())
+		_, err = repo.Get(ctx, alice.ID)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		_, err = repo.GetUser(ctx, alice.ID)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		all, _ := repo.GetAll(ctx)
+		g.Expect(*all).To(gomega.HaveLen(1))
+		_, total, _ := repo.List(ctx, UserListOptions{Limit: 10, SortField: "id"})
+		g.Expect(total).To(gomega.Equal(int64(1)))
+		listed, total, _ := repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", IncludeDeleted: true})
+		g.Expect(total).To(gomega.Equal(int64(2)))
+		deleted := (*listed)[0]
+		g.Expect(deleted.DeletedAt).NotTo(gomega.BeNil())
+		g.Expect(deleted.Version).To(gomega.Equal(uint(2)))
+		entity := User{ID: alice.ID, Username: "alice", Email: "alice@example.com", Age: 31, Version: deleted.Version}
+		_, err = repo.Update(ctx, entity)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+
+		// Deleted users only hold on to their values when asked to
+		unique, _ := repo.IsEmailUnique(ctx, nil, "alice@example.com", true)
+		g.Expect(unique).To(gomega.BeFalse())
+		unique, _ = repo.IsEmailUnique(ctx, nil, "alice@example.com", false)
+		g.Expect(unique).To(gomega.BeTrue())
+		unique, _ = repo.IsUsernameUnique(ctx, nil, "alice", false)
+		g.Expect(unique).To(gomega.BeTrue())
+		other, err := repo.Save(ctx, User{Username: "alice2", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		_, err = repo.Restore(ctx, alice.ID, nil, nil)
+		var conflict *ConflictError
+		g.Expect(errors.As(err, &conflict)).To(gomega.BeTrue())
+		g.Expect(conflict.Field).To(gomega.Equal("email"))
+		g.Expect(repo.Purge(ctx, other.ID, nil)).To(gomega.Succeed())
+
+		_, err = repo.Restore(ctx, alice.ID, &alice.Version, nil)
+		g.Expect(errors.Is(err, ErrVersionMismatch)).To(gomega.BeTrue())
+		restorer := "operator:1"
+		restored, err := repo.Restore(ctx, alice.ID, &deleted.Version, &restorer)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(restored.DeletedAt).To(gomega.BeNil())
+		g.Expect(restored.Version).To(gomega.Equal(uint(3)))
+		g.Expect(restored.UpdatedBy).To(gomega.Equal(restorer))
+		got, _ := repo.Get(ctx, alice.ID)
+		g.Expect(*got).To(gomega.Equal(*restored))
+		_, err = repo.Restore(ctx, 404, nil, nil)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+
+		// Purging removes users whether or not they are deleted
+		g.Expect(errors.Is(repo.Purge(ctx, alice.ID, &deleted.Version), ErrVersionMismatch)).To(gomega.BeTrue())
+		g.Expect(repo.Delete(ctx, alice.ID, nil)).To(gomega.Succeed())
+		g.Expect(repo.Purge(ctx, alice.ID, nil)).To(gomega.Succeed())
+		_, total, _ = repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", IncludeDeleted: true})
+		g.Expect(total).To(gomega.Equal(int64(1)))
+		g.Expect(errors.Is(repo.Purge(ctx, alice.ID, nil), gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+		_, err = repo.Restore(ctx, alice.ID, nil, nil)
+		g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+	})
+
+	t.Run("Writes set timestamps and bump the change marker", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		before := time.Now().UTC().Truncate(time.Second)
+		initial, err := repo.ChangeMarker(ctx)
+		g.Expect(err).To(gomega.BeNil())
+
+		saved, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(saved.CreatedAt).NotTo(gomega.BeTemporally("<", before))
+		g.Expect(saved.UpdatedAt).To(gomega.Equal(saved.CreatedAt))
+		marker, _ := repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(initial.Version + 1))
+		g.Expect(marker.ChangedAt).To(gomega.Equal(saved.UpdatedAt))
+
+		entity, _ := repo.GetUser(ctx, saved.ID)
+		entity.Age = 31
+		updated, err := repo.Update(ctx, *entity)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(updated.CreatedAt).To(gomega.Equal(saved.CreatedAt))
+		g.Expect(updated.UpdatedAt).NotTo(gomega.BeTemporally("<", saved.UpdatedAt))
+		got, _ := repo.Get(ctx, saved.ID)
+		g.Expect(*got).To(gomega.Equal(*updated))
+		marker, _ = repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(initial.Version + 2))
+
+		// Failed writes leave the marker alone
+		_, err = repo.Update(ctx, *entity)
+		g.Expect(errors.Is(err, ErrVersionMismatch)).To(gomega.BeTrue())
+		_, err = repo.Save(ctx, User{Username: "bob", Email: "alice@example.com", Age: 30})
+		g.Expect(err).NotTo(gomega.BeNil())
+		marker, _ = repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(initial.Version + 2))
+
+		g.Expect(repo.Delete(ctx, saved.ID, nil)).To(gomega.Succeed())
+		marker, _ = repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(initial.Version + 3))
+
+		// So do deletes of users that are already gone
+		g.Expect(repo.Delete(ctx, saved.ID, nil)).To(gomega.Succeed())
+		g.Expect(repo.Delete(ctx, 404, nil)).To(gomega.Succeed())
+		version := uint(1)
+		g.Expect(repo.Delete(ctx, 404, &version)).To(gomega.Succeed())
+		marker, _ = repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(initial.Version + 3))
+	})
+
+	t.Run("A missing change marker is recreated", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		sql, ok := repo.(*userRepository)
+		if !ok {
+			t.Skip("only databases keep the marker in a row")
+		}
+		g.Expect(sql.db.Where("table_name = ?", User{}.TableName()).Delete(&ChangeMarker{}).Error).To(gomega.Succeed())
+
+		saved, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		marker, err := repo.ChangeMarker(ctx)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(marker.Version).To(gomega.Equal(uint64(1)))
+		g.Expect(marker.ChangedAt).To(gomega.Equal(saved.UpdatedAt))
+
+		g.Expect(repo.Delete(ctx, saved.ID, nil)).To(gomega.Succeed())
+		marker, _ = repo.ChangeMarker(ctx)
+		g.Expect(marker.Version).To(gomega.Equal(uint64(2)))
+	})
+
+	t.Run("Authors are recorded and times filter the list", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		creator, editor := "operator:1", "api_key:1"
+
+		saved, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30, CreatedBy: &creator, UpdatedBy: &creator})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(saved.CreatedBy).To(gomega.Equal(creator))
+		g.Expect(saved.UpdatedBy).To(gomega.Equal(creator))
+
+		entity, _ := repo.GetUser(ctx, saved.ID)
+		entity.UpdatedBy = &editor
+		updated, err := repo.Update(ctx, *entity)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(updated.CreatedBy).To(gomega.Equal(creator))
+		g.Expect(updated.UpdatedBy).To(gomega.Equal(editor))
+		got, _ := repo.Get(ctx, saved.ID)
+		g.Expect(*got).To(gomega.Equal(*updated))
+
+		// Both bounds are exclusive
+		list := func(opts UserListOptions) int64 {
+			opts.Limit, opts.SortField = 10, "id"
+			_, total, err := repo.List(ctx, opts)
+			g.Expect(err).To(gomega.BeNil())
+			return total
+		}
+		earlier, later := updated.CreatedAt.Add(-time.Second), updated.UpdatedAt.Add(time.Second)
+		g.Expect(list(UserListOptions{CreatedAfter: &earlier})).To(gomega.Equal(int64(1)))
+		g.Expect(list(UserListOptions{CreatedAfter: &updated.CreatedAt})).To(gomega.Equal(int64(0)))
+		g.Expect(list(UserListOptions{UpdatedBefore: &later})).To(gomega.Equal(int64(1)))
+		g.Expect(list(UserListOptions{UpdatedBefore: &updated.UpdatedAt})).To(gomega.Equal(int64(0)))
+	})
+
+	t.Run("Changes are audited with who made them", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		audit := auditRepositoryFor(repo)
+		ctx := model.ContextWithPrincipal(ctx, &model.Principal{ID: 1, Kind: "operator"})
+		ctx = model.ContextWithRequestMeta(ctx, model.RequestMeta{RequestID: "req-1", SourceIP: "192.0.2.1"})
+
+		alice, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		bob, _ := repo.Save(context.Background(), User{Username: "bob", Email: "bob@example.com", Age: 40})
+		entity, _ := repo.GetUser(ctx, alice.ID)
+		entity.Age = 31
+		_, err = repo.Update(ctx, *entity)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(repo.Delete(ctx, alice.ID, nil)).To(gomega.Succeed())
+		g.Expect(repo.Delete(ctx, alice.ID, nil)).To(gomega.Succeed())
+		_, err = repo.Restore(ctx, alice.ID, nil, nil)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(repo.Purge(ctx, alice.ID, nil)).To(gomega.Succeed())
+
+		// Failed writes leave no entry
+		_, err = repo.Update(ctx, *entity)
+		g.Expect(err).NotTo(gomega.BeNil())
+
+		history, err := audit.List(ctx, UserAuditListOptions{Limit: 10, UserID: &alice.ID})
+		g.Expect(err).To(gomega.BeNil())
+		actions := make([]string, len(*history))
+		for i, entry := range *history {
+			actions[i] = entry.Action
+			g.Expect(entry.Actor).To(gomega.Equal("operator:1"))
+			g.Expect(entry.RequestID).To(gomega.Equal("req-1"))
+			g.Expect(entry.SourceIP).To(gomega.Equal("192.0.2.1"))
+		}
+		g.Expect(actions).To(gomega.Equal([]string{model.AuditPurge, model.AuditRestore, model.AuditDelete, model.AuditUpdate, model.AuditCreate}))
+		g.Expect((*history)[3].Changes).To(gomega.Equal(map[string]model.FieldChange{"age": {Before: float64(30), After: float64(31)}}))
+		g.Expect((*history)[4].Changes).To(gomega.HaveKeyWithValue("username", model.FieldChange{After: "alice"}))
+		g.Expect((*history)[2].Changes).To(gomega.HaveKey("deleted_at"))
+		g.Expect((*history)[2].Changes["deleted_at"].Before).To(gomega.BeNil())
+		g.Expect((*history)[0].Changes).To(gomega.HaveKeyWithValue("email", model.FieldChange{Before: "alice@example.com"}))
+
+		all, _ := audit.List(ctx, UserAuditListOptions{Limit: 10})
+		g.Expect(*all).To(gomega.HaveLen(6))
+		anonymous, _ := audit.List(ctx, UserAuditListOptions{Limit: 10, UserID: &bob.ID})
+		g.Expect(*anonymous).To(gomega.HaveLen(1))
+		g.Expect((*anonymous)[0].Actor).To(gomega.BeEmpty())
+		page, _ := audit.List(ctx, UserAuditListOptions{Limit: 2, BeforeID: (*history)[1].ID, Action: model.AuditCreate})
+		g.Expect(*page).To(gomega.HaveLen(2))
+		g.Expect((*page)[0].UserID).To(gomega.Equal(bob.ID))
+		filtered, _ := audit.List(ctx, UserAuditListOptions{Limit: 10, Actor: "operator:1", RequestID: "req-1"})
+		g.Expect(*filtered).To(gomega.HaveLen(5))
+		from, to := (*history)[4].OccurredAt, (*history)[0].OccurredAt.Add(time.Second)
+		inRange, _ := audit.List(ctx, UserAuditListOptions{Limit: 10, From: &from, To: &to})
+		g.Expect(*inRange).To(gomega.HaveLen(6))
+		inRange, _ = audit.List(ctx, UserAuditListOptions{Limit: 10, To: &from})
+		g.Expect(*inRange).To(gomega.BeEmpty())
+	})
+
+	t.Run("Concurrent updates of one version keep a single change", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		saved, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		entity, _ := repo.GetUser(ctx, saved.ID)
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int, user User) {
+				defer wg.Done()
+				user.Age = 40 + i
+				_, errs[i] = repo.Update(ctx, user)
+			}(i, *entity)
+		}
+		wg.Wait()
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+			} else {
+				g.Expect(errors.Is(err, ErrVersionMismatch)).To(gomega.BeTrue(), err.Error())
+			}
+		}
+		g.Expect(succeeded).To(gomega.Equal(1))
+		got, _ := repo.Get(ctx, saved.ID)
+		g.Expect(got.Version).To(gomega.Equal(uint(2)))
+	})
+
+	t.Run("Duplicate emails and usernames are conflicts", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		_, err := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		g.Expect(err).To(gomega.BeNil())
+		bob, err := repo.Save(ctx, User{Username: "bob", Email: "bob@example.com", Age: 40})
+		g.Expect(err).To(gomega.BeNil())
+
+		var conflict *ConflictError
+		_, err = repo.Save(ctx, User{Username: "carol", Email: "Alice@Example.com", Age: 50})
+		g.Expect(errors.As(err, &conflict)).To(gomega.BeTrue())
+		g.Expect(conflict.Field).To(gomega.Equal("email"))
+		_, err = repo.Save(ctx, User{Username: "ALICE", Email: "carol@example.com", Age: 50})
+		g.Expect(errors.As(err, &conflict)).To(gomega.BeTrue())
+		g.Expect(conflict.Field).To(gomega.Equal("username"))
+
+		entity, _ := repo.GetUser(ctx, bob.ID)
+		entity.Username = "alice"
+		_, err = repo.Update(ctx, *entity)
+		g.Expect(errors.As(err, &conflict)).To(gomega.BeTrue())
+		g.Expect(conflict.Field).To(gomega.Equal("username"))
+	})
+
+	t.Run("Concurrent saves of one email keep a single user", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = repo.Save(ctx, User{Username: fmt.Sprintf("user%d", i), Email: "same@example.com", Age: 30})
+			}(i)
+		}
+		wg.Wait()
+		succeeded := 0
+		for _, err := range errs {
+			var conflict *ConflictError
+			if err == nil {
+				succeeded++
+			} else {
+				g.Expect(errors.As(err, &conflict)).To(gomega.BeTrue(), err.Error())
+			}
+		}
+		g.Expect(succeeded).To(gomega.Equal(1))
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		users, err := repo.GetAll(ctx)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(*users).To(gomega.BeEmpty())
+
+		alice, _ := repo.Save(ctx, User{Username: "alice", Email: "alice@example.com", Age: 30})
+		bob, _ := repo.Save(ctx, User{Username: "bob", Email: "bob@example.org", Age: 40})
+		users, _ = repo.GetAll(ctx)
+		g.Expect(*users).To(gomega.ConsistOf(*alice, *bob))
+	})
+
+	t.Run("List filters, sorts and pages", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		repo := newRepository(t)
+		for i, age := range []int{25, 40, 40, 60, 18} {
+			domain := "example.com"
+			if i%2 == 1 {
+				domain = "example.org"
+			}
+			_, err := repo.Save(ctx, User{Username: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@%s", i, domain), Age: age})
+			g.Expect(err).To(gomega.BeNil())
+		}
+
+		minAge, maxAge := 20, 50
+		users, total, err := repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", EmailDomain: "example.com", MinAge: &minAge, MaxAge: &maxAge})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(total).To(gomega.Equal(int64(2)))
+		g.Expect(usernames(*users)).To(gomega.Equal([]string{"user0", "user2"}))
+
+		users, total, _ = repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", UsernamePrefix: "user1"})
+		g.Expect(total).To(gomega.Equal(int64(1)))
+		g.Expect(usernames(*users)).To(gomega.Equal([]string{"user1"}))
+
+		// Filters ignore case
+		users, _, _ = repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", UsernamePrefix: "USER1"})
+		g.Expect(usernames(*users)).To(gomega.Equal([]string{"user1"}))
+		users, _, _ = repo.List(ctx, UserListOptions{Limit: 10, SortField: "id", EmailDomain: "Example.ORG"})
+		g.Expect(usernames(*users)).To(gomega.Equal([]string{"user1", "user3"}))
+
+		// Ties on the sort field are broken by ID in the same direction
+		first, total, _ := repo.List(ctx, UserListOptions{Limit: 2, SortField: "age", Descending: true})
+		g.Expect(total).To(gomega.Equal(int64(5)))
+		g.Expect(usernames(*first)).To(gomega.Equal([]string{"user3", "user2"}))
+		last := (*first)[1]
+		rest, _, _ := repo.List(ctx, UserListOptions{Limit: 10, SortField: "age", Descending: true, AfterValue: float64(last.Age), AfterID: last.ID})
+		g.Expect(usernames(*rest)).To(gomega.Equal([]string{"user1", "user0", "user4"}))
+
+		rest, _, _ = repo.List(ctx, UserListOptions{Limit: 10, SortField: "username", AfterValue: "user2", AfterID: (*first)[1].ID})
+		g.Expect(usernames(*rest)).To(gomega.Equal([]string{"user3", "user4"}))
+	})
+}
+
+func usernames(users []model.User) []string {
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Username
+	}
+	return names
+}
+
+// Audit log kept by repo
+func auditRepositoryFor(repo UserRepository) UserAuditRepository {
+	if sql, ok := repo.(*userRepository); ok {
+		return NewUserAuditRepository(sql.db, config.DBConfig{QueryTimeout: sql.timeout})
+	}
+	return SelectUserAuditRepository(nil, config.DBConfig{}, repo)
+}
+
+func TestUserMemoryRepository(t *testing.T) {
+	testUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewUserMemoryRepository()
+	})
+}
+
+func TestUserMemoryRepository_ConcurrentSaves(t *testing.T) {
+	g := gomega.NewWithT(t)
+	repo := NewUserMemoryRepository()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repo.Save(context.Background(), User{Username: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: i})
+		}(i)
+	}
+	wg.Wait()
+	users, _ := repo.GetAll(context.Background())
+	g.Expect(*users).To(gomega.HaveLen(50))
+	for i, user := range *users {
+		g.Expect(user.ID).To(gomega.Equal(uint(i + 1)))
+	}
+}
+
+func TestUserRepository_SQLite(t *testing.T) {
+	cfg := config.DBConfig{Driver: "sqlite", DSN: config.SQLiteDSN(filepath.Join(t.TempDir(), "atmail.db")), MaxOpenConns: 1}
+	testUserRepositoryContract(t, newTestDatabase(t, cfg))
+}
+
+// Runs against the database in TEST_MYSQL_DSN, whose migrations are rolled
+// back and reapplied before each case
+func TestUserRepository_MySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	testUserRepositoryContract(t, newTestDatabase(t, config.DBConfig{Driver: "mysql", DSN: dsn, MaxOpenConns: 5}))
+}
+
+// Runs against the database in TEST_POSTGRES_DSN, whose migrations are rolled
+// back and reapplied before each case
+func TestUserRepository_Postgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	testUserRepositoryContract(t, newTestDatabase(t, config.DBConfig{Driver: "postgres", DSN: dsn, MaxOpenConns: 5}))
+}
+
+// Connect to cfg and return a constructor that recreates the schema with the
+// migrations and hands out a repository on the empty tables
+func newTestDatabase(t *testing.T, cfg config.DBConfig) func(t *testing.T) UserRepository {
+	db, cleanup, err := NewDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func(t *testing.T) UserRepository {
+		if err := migrator.To(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return NewUserRepository(db, cfg)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix = "atm"
+	// How often last_used_at is written for a key that is used repeatedly
+	apiKeyTouchInterval = time.Minute
+	// Longest chain of keys minted by keys that is followed to the operator
+	// at its start
+	maxApiKeyChain = 8
+)
+
+var (
+	ErrInvalidApiKey  = &UnauthorizedError{Message: "invalid, expired or revoked API key"}
+	ErrApiKeyNotFound = &NotFoundError{Resource: "API key"}
+)
+
+type apiKeyService struct {
+	apiKeyRepository repository.ApiKeyRepository
+	operatorService  OperatorService
+}
+
+type ApiKeyService interface {
+	Authenticate(ctx context.Context, key string) (*model.Principal, error)
+	Create(ctx context.Context, req model.ApiKeyRequest, creator *model.Principal) (*model.ApiKeyCreated, error)
+	GetAll(ctx context.Context) (*[]model.ApiKey, error)
+	Revoke(ctx context.Context, id uint) error
+}
+
+func NewApiKeyService(repository repository.ApiKeyRepository, operators OperatorService) ApiKeyService {
+	service := new(apiKeyService)
+	service.apiKeyRepository = repository
+	service.operatorService = operators
+	return service
+}
+
+// Check an API key and return the principal it authenticates. Keys look like
+// atm_<prefix>_<secret>; the prefix identifies the row and the whole key is
+// compared against the stored hash. A key only keeps the scopes its creator
+// still holds, so it stops working when its creator is disabled.
+func (a *apiKeyService) Authenticate(ctx context.Context, key string) (*model.Principal, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidApiKey
+	}
+	stored, err := a.apiKeyRepository.GetByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidApiKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(key)), []byte(stored.KeyHash)) != 1 {
+		return nil, ErrInvalidApiKey
+	}
+	now := time.Now()
+	scopes, err := a.scopes(ctx, stored, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidApiKey
+	}
+
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) > apiKeyTouchInterval {
+		if err := a.apiKeyRepository.Touch(ctx, stored.ID, now); err != nil {
+			log.Warnf("Error updating last use of API key %s: %s", stored.Prefix, err.Error())
+		}
+	}
+
+	return &model.Principal{
+		ID:          stored.ID,
+		Name:        stored.Name,
+		Kind:        model.PrincipalApiKey,
+		Permissions: scopes,
+	}, nil
+}
+
+// Scopes a key can use now: none when it is revoked or expired, otherwise
+// its own less those its creator no longer holds. depth counts the keys
+// followed so far through keys minted by keys.
+func (a *apiKeyService) scopes(ctx context.Context, key *repository.ApiKey, now time.Time, depth int) ([]model.Permission, error) {
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil
+	}
+	held, err := a.creatorPermissions(ctx, key.CreatedBy, now, depth)
+	if err != nil {
+		return nil, err
+	}
+	var scopes []model.Permission
+	for _, scope := range toApiKeyModel(key).Scopes {
+		for _, permission := range held {
+			if scope == permission {
+				scopes = append(scopes, scope)
+				break
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// Current permissions of the principal that minted a key: an operator's
+// role, or the usable scopes of a key. Missing and disabled creators hold
+// none. Bare IDs from before created_by named the kind are taken to be
+// operators.
+func (a *apiKeyService) creatorPermissions(ctx context.Context, createdBy string, now time.Time, depth int) ([]model.Permission, error) {
+	kind, ref, ok := strings.Cut(createdBy, ":")
+	if !ok {
+		kind, ref = model.PrincipalOperator, createdBy
+	}
+	id, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case model.PrincipalOperator:
+		operator, err := a.operatorService.Get(ctx, uint(id))
+		if err != nil {
+			if errors.Is(err, ErrOperatorNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if operator.Disabled {
+			return nil, nil
+		}
+		return model.RolePermissions[operator.Role], nil
+	case model.PrincipalApiKey:
+		if depth >= maxApiKeyChain {
+			return nil, nil
+		}
+		creator, err := a.apiKeyRepository.Get(ctx, uint(id))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return a.scopes(ctx, creator, now, depth+1)
+	}
+	return nil, nil
+}
+
+// Mint a new API key. Keys can only be given scopes their creator holds.
+func (a *apiKeyService) Create(ctx context.Context, req model.ApiKeyRequest, creator *model.Principal) (*model.ApiKeyCreated, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	var errs []FieldError
+	if req.Name == "" {
+		errs = append(errs, FieldError{Field: "name", Code: "name_required", Message: "name is required"})
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, FieldError{Field: "scopes", Code: "scopes_required", Message: "at least one scope is required"})
+	}
+	for _, scope := range req.Scopes {
+		if !creator.HasPermission(scope) {
+			errs = append(errs, FieldError{Field: "scopes", Code: "scope_invalid", Message: "invalid scope: " + string(scope)})
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errs = append(errs, FieldError{Field: "expires_at", Code: "expires_at_invalid", Message: "expires_at must be in the future"})
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	// The secret is base64url and may contain underscores, which would
+	// clash with the separator
+	key := apiKeyPrefix + "_" + prefix + "_" + strings.ReplaceAll(secret, "_", "-")
+
+	scopes := make([]string, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = string(scope)
+	}
+	saved, err := a.apiKeyRepository.Save(ctx, repository.ApiKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: creator.Ref(),
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return &model.ApiKeyCreated{ApiKey: *toApiKeyModel(saved), Key: key}, nil
+}
+
+// Get all API keys
+func (a *apiKeyService) GetAll(ctx context.Context) (*[]model.ApiKey, error) {
+	keys, err := a.apiKeyRepository.GetAll(ctx)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	m := make([]model.ApiKey, len(*keys))
+	for i := range *keys {
+		m[i] = *toApiKeyModel(&(*keys)[i])
+	}
+	return &m, nil
+}
+
+// Revoke an API key
+func (a *apiKeyService) Revoke(ctx context.Context, id uint) error {
+	if _, err := a.apiKeyRepository.Get(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrApiKeyNotFound
+		}
+		return internalError(err)
+	}
+	if err := a.apiKeyRepository.Revoke(ctx, id); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toApiKeyModel(key *repository.ApiKey) *model.ApiKey {
+	scopes := []model.Permission{}
+	for _, scope := range strings.Split(key.Scopes, ",") {
+		if scope != "" {
+			scopes = append(scopes, model.Permission(scope))
+		}
+	}
+	return &model.ApiKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+	}
+}
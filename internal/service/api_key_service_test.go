@@ -0,0 +1,229 @@
+package service
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MockApiKeyStore struct {
+	keys []repository.ApiKey
+}
+
+func (a *MockApiKeyStore) Get(ctx context.Context, id uint) (*repository.ApiKey, error) {
+	for _, key := range a.keys {
+		if key.ID == id {
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (a *MockApiKeyStore) GetAll(ctx context.Context) (*[]repository.ApiKey, error) {
+	return &a.keys, nil
+}
+
+func (a *MockApiKeyStore) GetByPrefix(ctx context.Context, prefix string) (*repository.ApiKey, error) {
+	for _, key := range a.keys {
+		if key.Prefix == prefix {
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (a *MockApiKeyStore) Revoke(ctx context.Context, id uint) error {
+	now := time.Now()
+	for i := range a.keys {
+		if a.keys[i].ID == id {
+			a.keys[i].RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (a *MockApiKeyStore) Save(ctx context.Context, key repository.ApiKey) (*repository.ApiKey, error) {
+	key.ID = uint(len(a.keys) + 1)
+	a.keys = append(a.keys, key)
+	return &key, nil
+}
+
+func (a *MockApiKeyStore) Touch(ctx context.Context, id uint, usedAt time.Time) error {
+	for i := range a.keys {
+		if a.keys[i].ID == id {
+			a.keys[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+// API key service whose keys are minted by operator 1, an admin, and
+// operator 2, a helpdesk operator
+func newTestApiKeyService(store *MockApiKeyStore) *apiKeyService {
+	operators := newMockOperatorStore(
+		repository.Operator{ID: 1, Username: "admin", Role: model.RoleAdmin},
+		repository.Operator{ID: 2, Username: "helpdesk", Role: model.RoleHelpdesk},
+	)
+	return &apiKeyService{apiKeyRepository: store, operatorService: &operatorService{operatorRepository: operators}}
+}
+
+func Test_apiKeyService_Create(t *testing.T) {
+	admin := model.NewOperatorPrincipal(&model.Operator{ID: 1, Role: model.RoleAdmin})
+	helpdesk := model.NewOperatorPrincipal(&model.Operator{ID: 2, Role: model.RoleHelpdesk})
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		req     model.ApiKeyRequest
+		creator *model.Principal
+		// Codes of the expected validation errors
+		wantErr []string
+	}{
+		{name: "should create key", req: model.ApiKeyRequest{Name: "provisioning", Scopes: []model.Permission{model.UsersRead, model.UsersWrite}}, creator: admin},
+		{name: "should reject missing name", req: model.ApiKeyRequest{Scopes: []model.Permission{model.UsersRead}}, creator: admin, wantErr: []string{"name_required"}},
+		{name: "should reject missing scopes", req: model.ApiKeyRequest{Name: "provisioning"}, creator: admin, wantErr: []string{"scopes_required"}},
+		{name: "should reject unknown scope", req: model.ApiKeyRequest{Name: "provisioning", Scopes: []model.Permission{"users:all"}}, creator: admin, wantErr: []string{"scope_invalid"}},
+		{name: "should reject scope the creator lacks", req: model.ApiKeyRequest{Name: "provisioning", Scopes: []model.Permission{model.UsersDelete}}, creator: helpdesk, wantErr: []string{"scope_invalid"}},
+		{name: "should reject past expiry", req: model.ApiKeyRequest{Name: "provisioning", Scopes: []model.Permission{model.UsersRead}, ExpiresAt: &past}, creator: admin, wantErr: []string{"expires_at_invalid"}},
+		{name: "should report every invalid field", req: model.ApiKeyRequest{Scopes: []model.Permission{"users:all"}, ExpiresAt: &past}, creator: admin, wantErr: []string{"name_required", "scope_invalid", "expires_at_invalid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockApiKeyStore{}
+			a := newTestApiKeyService(store)
+			got, err := a.Create(context.Background(), tt.req, tt.creator)
+			if tt.wantErr != nil {
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("apiKeyService.Create() error = %v, want a ValidationError", err)
+				}
+				var codes []string
+				for _, e := range verr.Errors {
+					codes = append(codes, e.Code)
+				}
+				if !reflect.DeepEqual(codes, tt.wantErr) {
+					t.Errorf("apiKeyService.Create() error codes = %v, want %v", codes, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apiKeyService.Create() error = %v", err)
+			}
+			if got.CreatedBy != "operator:1" || store.keys[0].CreatedBy != "operator:1" {
+				t.Errorf("apiKeyService.Create() created_by = %q, want operator:1", got.CreatedBy)
+			}
+			principal, err := a.Authenticate(context.Background(), got.Key)
+			if err != nil {
+				t.Fatalf("apiKeyService.Authenticate() error = %v", err)
+			}
+			if principal.Kind != model.PrincipalApiKey || !principal.HasPermission(model.UsersWrite) || principal.HasPermission(model.UsersDelete) {
+				t.Errorf("apiKeyService.Authenticate() = %+v", principal)
+			}
+		})
+	}
+}
+
+func Test_apiKeyService_Authenticate(t *testing.T) {
+	admin := model.NewOperatorPrincipal(&model.Operator{ID: 1, Role: model.RoleAdmin})
+	store := &MockApiKeyStore{}
+	a := newTestApiKeyService(store)
+	expiry := time.Now().Add(time.Hour)
+	created, err := a.Create(context.Background(), model.ApiKeyRequest{Name: "jobs", Scopes: []model.Permission{model.UsersRead}, ExpiresAt: &expiry}, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Authenticate(context.Background(), created.Key); err != nil {
+		t.Fatalf("apiKeyService.Authenticate() error = %v", err)
+	}
+	if store.keys[0].LastUsedAt == nil {
+		t.Errorf("apiKeyService.Authenticate() did not record last use")
+	}
+	if _, err := a.Authenticate(context.Background(), created.Key+"x"); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() tampered key error = %v, want %v", err, ErrInvalidApiKey)
+	}
+	if _, err := a.Authenticate(context.Background(), "garbage"); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() malformed key error = %v, want %v", err, ErrInvalidApiKey)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	store.keys[0].ExpiresAt = &past
+	if _, err := a.Authenticate(context.Background(), created.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() expired key error = %v, want %v", err, ErrInvalidApiKey)
+	}
+
+	store.keys[0].ExpiresAt = nil
+	if err := a.Revoke(context.Background(), created.ID); err != nil {
+		t.Fatalf("apiKeyService.Revoke() error = %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), created.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() revoked key error = %v, want %v", err, ErrInvalidApiKey)
+	}
+	if err := a.Revoke(context.Background(), 100); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Errorf("apiKeyService.Revoke() error = %v, want %v", err, ErrApiKeyNotFound)
+	}
+}
+
+func Test_apiKeyService_AuthenticateFollowsCreator(t *testing.T) {
+	ctx := context.Background()
+	store := &MockApiKeyStore{}
+	a := newTestApiKeyService(store)
+	admin := model.NewOperatorPrincipal(&model.Operator{ID: 1, Role: model.RoleAdmin})
+	parent, err := a.Create(ctx, model.ApiKeyRequest{Name: "provisioning", Scopes: []model.Permission{model.UsersRead, model.UsersWrite, model.ApiKeysManage}}, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentPrincipal, err := a.Authenticate(ctx, parent.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := a.Create(ctx, model.ApiKeyRequest{Name: "jobs", Scopes: []model.Permission{model.UsersRead, model.UsersWrite}}, parentPrincipal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A downgraded creator caps its keys, and the keys those keys minted
+	if err := a.operatorService.SetRole(ctx, "admin", model.RoleHelpdesk); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{parent.Key, child.Key} {
+		principal, err := a.Authenticate(ctx, key)
+		if err != nil {
+			t.Fatalf("apiKeyService.Authenticate() error = %v", err)
+		}
+		if !reflect.DeepEqual(principal.Permissions, []model.Permission{model.UsersRead}) {
+			t.Errorf("apiKeyService.Authenticate() permissions = %v, want [%s]", principal.Permissions, model.UsersRead)
+		}
+	}
+
+	// Revoking a key disables the keys it minted
+	if err := a.Revoke(ctx, parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(ctx, child.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() key of revoked key error = %v, want %v", err, ErrInvalidApiKey)
+	}
+
+	// Keys from before created_by named the kind belong to the operator
+	legacy, err := a.Create(ctx, model.ApiKeyRequest{Name: "legacy", Scopes: []model.Permission{model.UsersRead}}, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.keys[len(store.keys)-1].CreatedBy = "1"
+	if _, err := a.Authenticate(ctx, legacy.Key); err != nil {
+		t.Errorf("apiKeyService.Authenticate() legacy key error = %v", err)
+	}
+
+	// Keys of a disabled operator stop working
+	if err := a.operatorService.Disable(ctx, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(ctx, legacy.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("apiKeyService.Authenticate() key of disabled operator error = %v, want %v", err, ErrInvalidApiKey)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"atmail/internal/helper"
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"atmail/internal/tracing"
+	"context"
+	"fmt"
+)
+
+// Audit entries are only listed newest first
+const auditCursorSort = "id:desc"
+
+var auditActions = map[string]bool{
+	model.AuditCreate:  true,
+	model.AuditUpdate:  true,
+	model.AuditDelete:  true,
+	model.AuditRestore: true,
+	model.AuditPurge:   true,
+}
+
+type auditService struct {
+	auditRepository repository.UserAuditRepository
+}
+
+type AuditService interface {
+	List(ctx context.Context, query model.AuditQuery) (*model.UserAuditList, error)
+}
+
+func NewAuditService(repository repository.UserAuditRepository) AuditService {
+	service := new(auditService)
+	service.auditRepository = repository
+	return service
+}
+
+// List a page of user audit entries matching the query, newest first
+func (a *auditService) List(ctx context.Context, query model.AuditQuery) (*model.UserAuditList, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AuditService.List")
+	defer span.End()
+	opts, err := a.listOptions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra entry to find out if there is a next page
+	limit := opts.Limit
+	opts.Limit++
+	entries, err := a.auditRepository.List(ctx, *opts)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	list := &model.UserAuditList{Items: []model.UserAuditEntry{}}
+	if entries != nil {
+		list.Items = *entries
+	}
+	if len(list.Items) > limit {
+		list.Items = list.Items[:limit]
+		next, err := helper.EncodeCursor(helper.Cursor{Sort: auditCursorSort, ID: list.Items[limit-1].ID})
+		if err != nil {
+			return nil, internalError(err)
+		}
+		list.NextCursor = next
+	}
+	return list, nil
+}
+
+// validate the audit query and convert it to repository options
+func (a *auditService) listOptions(query model.AuditQuery) (*repository.UserAuditListOptions, error) {
+	opts := repository.UserAuditListOptions{
+		Limit:     defaultListLimit,
+		UserID:    query.UserID,
+		Action:    query.Action,
+		Actor:     query.Actor,
+		RequestID: query.RequestID,
+		From:      query.From,
+		To:        query.To,
+	}
+
+	if query.Limit < 0 || query.Limit > maxListLimit {
+		return nil, invalidField("limit", "limit_out_of_range", fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	if query.Limit > 0 {
+		opts.Limit = query.Limit
+	}
+	if query.Action != "" && !auditActions[query.Action] {
+		return nil, invalidField("action", "action_invalid", "invalid audit action")
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, invalidField("from", "time_range_invalid", "from must be before to")
+	}
+
+	if query.Cursor != "" {
+		cursor, err := helper.DecodeCursor(query.Cursor)
+		if err != nil {
+			return nil, invalidField("cursor", "cursor_invalid", err.Error())
+		}
+		if cursor.Sort != auditCursorSort {
+			return nil, invalidField("cursor", "cursor_sort_mismatch", "cursor does not belong to the audit log")
+		}
+		opts.BeforeID = cursor.ID
+	}
+	return &opts, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type MockAuditStore struct {
+	entries []model.UserAuditEntry
+	opts    repository.UserAuditListOptions
+	err     error
+}
+
+func (a *MockAuditStore) List(ctx context.Context, opts repository.UserAuditListOptions) (*[]model.UserAuditEntry, error) {
+	a.opts = opts
+	if a.err != nil {
+		return nil, a.err
+	}
+	entries := a.entries
+	if len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+	return &entries, nil
+}
+
+func Test_auditService_List(t *testing.T) {
+	entries := []model.UserAuditEntry{{ID: 3, UserID: 1}, {ID: 2, UserID: 2}, {ID: 1, UserID: 1}}
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	tests := []struct {
+		name      string
+		store     *MockAuditStore
+		query     model.AuditQuery
+		wantItems int
+		wantNext  bool
+		wantErr   bool
+	}{
+		{name: "should return all entries", store: &MockAuditStore{entries: entries}, query: model.AuditQuery{}, wantItems: 3},
+		{name: "should return first page with cursor", store: &MockAuditStore{entries: entries}, query: model.AuditQuery{Limit: 2}, wantItems: 2, wantNext: true},
+		{name: "should accept a known action", store: &MockAuditStore{entries: entries}, query: model.AuditQuery{Action: model.AuditRestore}, wantItems: 3},
+		{name: "should reject invalid limit", store: &MockAuditStore{}, query: model.AuditQuery{Limit: 1000}, wantErr: true},
+		{name: "should reject invalid action", store: &MockAuditStore{}, query: model.AuditQuery{Action: "read"}, wantErr: true},
+		{name: "should reject invalid cursor", store: &MockAuditStore{}, query: model.AuditQuery{Cursor: "abc"}, wantErr: true},
+		{name: "should reject inverted time range", store: &MockAuditStore{}, query: model.AuditQuery{From: &later, To: &earlier}, wantErr: true},
+		{name: "should fail to return entries", store: &MockAuditStore{err: errors.New("connection refused")}, query: model.AuditQuery{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &auditService{
+				auditRepository: tt.store,
+			}
+			got, err := a.List(context.Background(), tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("auditService.List() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Items) != tt.wantItems {
+				t.Errorf("auditService.List() items = %v, want %v", len(got.Items), tt.wantItems)
+			}
+			if (got.NextCursor != "") != tt.wantNext {
+				t.Errorf("auditService.List() next cursor = %q, wantNext %v", got.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func Test_auditService_ListCursor(t *testing.T) {
+	store := &MockAuditStore{entries: []model.UserAuditEntry{{ID: 3}, {ID: 2}, {ID: 1}}}
+	a := &auditService{auditRepository: store}
+	first, err := a.List(context.Background(), model.AuditQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("auditService.List() error = %v", err)
+	}
+	if _, err := a.List(context.Background(), model.AuditQuery{Limit: 1, Cursor: first.NextCursor}); err != nil {
+		t.Fatalf("auditService.List() error = %v", err)
+	}
+	if store.opts.BeforeID != 3 {
+		t.Errorf("auditService.List() before = %v, want 3", store.opts.BeforeID)
+	}
+
+	users := &userService{userRepository: &MockUser{}}
+	page, _ := users.List(context.Background(), model.UserQuery{Limit: 1})
+	if _, err := a.List(context.Background(), model.AuditQuery{Cursor: page.NextCursor}); err == nil {
+		t.Errorf("auditService.List() expected error for cursor of the user list")
+	}
+}
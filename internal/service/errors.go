@@ -0,0 +1,92 @@
+package service
+
+import "strings"
+
+// Errors returned by the services. Handlers decide the response from the
+// type; anything else is an internal failure whose details stay in the logs.
+
+// The requested resource does not exist
+type NotFoundError struct {
+	Resource string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " not found"
+}
+
+// A problem with one field of a request. Code is stable for clients to
+// match on; Message is for people.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// The request is invalid. Errors lists every invalid field found.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return joinFieldErrors(e.Errors)
+}
+
+// The request conflicts with the current state: another user already has the
+// email or username, a JSON Patch test operation failed, or another request
+// changed the user while it was being updated. Taken values are reported by
+// validation and, when a concurrent request saved the same value first, by
+// Save, Update and Patch.
+type ConflictError struct {
+	Errors []FieldError
+}
+
+func (e *ConflictError) Error() string {
+	return joinFieldErrors(e.Errors)
+}
+
+// The resource no longer has the version the caller expected
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+// The caller could not be authenticated
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+// A dependency such as the database failed
+type InternalError struct {
+	Err error
+}
+
+func (e *InternalError) Error() string {
+	return "internal error: " + e.Err.Error()
+}
+
+func (e *InternalError) Unwrap() error {
+	return e.Err
+}
+
+func invalidField(field, code, message string) error {
+	return &ValidationError{Errors: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func joinFieldErrors(errs []FieldError) string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func internalError(err error) error {
+	return &InternalError{Err: err}
+}
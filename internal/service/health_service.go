@@ -0,0 +1,112 @@
+package service
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Dependency that must be reachable for the server to be ready
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) (map[string]interface{}, error)
+}
+
+type healthService struct {
+	mu           sync.RWMutex
+	checkers     []HealthChecker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+type HealthService interface {
+	Live() model.Health
+	Ready(ctx context.Context) model.Health
+	Register(checker HealthChecker)
+	SetShuttingDown()
+}
+
+func NewHealthService(db *repository.DBHealthChecker, cfg config.HealthConfig) HealthService {
+	service := new(healthService)
+	service.timeout = cfg.CheckTimeout
+	service.Register(db)
+	return service
+}
+
+// The process is up and serving requests
+func (h *healthService) Live() model.Health {
+	return model.Health{Status: model.HealthOK, Checks: []model.HealthCheck{}}
+}
+
+// Run every registered check concurrently, each bounded by the check timeout
+func (h *healthService) Ready(ctx context.Context) model.Health {
+	h.mu.RLock()
+	checkers := append([]HealthChecker(nil), h.checkers...)
+	h.mu.RUnlock()
+
+	checks := make([]model.HealthCheck, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker HealthChecker) {
+			defer wg.Done()
+			checks[i] = h.run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	if h.shuttingDown.Load() {
+		checks = append(checks, model.HealthCheck{
+			Name:   "shutdown",
+			Status: model.HealthFail,
+			Error:  "server is shutting down",
+		})
+	}
+
+	health := model.Health{Status: model.HealthOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != model.HealthOK {
+			health.Status = model.HealthFail
+		}
+	}
+	return health
+}
+
+// Add a dependency to the readiness checks
+func (h *healthService) Register(checker HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, checker)
+}
+
+// Make readiness fail so load balancers stop routing new requests here
+func (h *healthService) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *healthService) run(ctx context.Context, checker HealthChecker) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := checker.Check(ctx)
+	check := model.HealthCheck{
+		Name:      checker.Name(),
+		Status:    model.HealthOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		check.Status = model.HealthFail
+		check.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			check.Error = "timed out after " + h.timeout.String()
+		}
+	}
+	return check
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"atmail/internal/model"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type MockChecker struct {
+	name  string
+	delay time.Duration
+	err   error
+}
+
+func (m *MockChecker) Name() string {
+	return m.name
+}
+
+func (m *MockChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	select {
+	case <-time.After(m.delay):
+		return map[string]interface{}{"checked": true}, m.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func Test_healthService_Ready(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkers     []HealthChecker
+		shuttingDown bool
+		wantStatus   string
+		wantChecks   []string
+	}{
+		{name: "should be ready", checkers: []HealthChecker{&MockChecker{name: "database"}}, wantStatus: model.HealthOK, wantChecks: []string{model.HealthOK}},
+		{name: "should fail when a check fails", checkers: []HealthChecker{&MockChecker{name: "database"}, &MockChecker{name: "cache", err: errors.New("connection refused")}}, wantStatus: model.HealthFail, wantChecks: []string{model.HealthOK, model.HealthFail}},
+		{name: "should fail when a check times out", checkers: []HealthChecker{&MockChecker{name: "database", delay: time.Second}}, wantStatus: model.HealthFail, wantChecks: []string{model.HealthFail}},
+		{name: "should fail while shutting down", checkers: []HealthChecker{&MockChecker{name: "database"}}, shuttingDown: true, wantStatus: model.HealthFail, wantChecks: []string{model.HealthOK, model.HealthFail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &healthService{timeout: 50 * time.Millisecond}
+			for _, checker := range tt.checkers {
+				h.Register(checker)
+			}
+			if tt.shuttingDown {
+				h.SetShuttingDown()
+			}
+
+			got := h.Ready(context.Background())
+			if got.Status != tt.wantStatus {
+				t.Errorf("healthService.Ready() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if len(got.Checks) != len(tt.wantChecks) {
+				t.Fatalf("healthService.Ready() checks = %+v, want %v", got.Checks, tt.wantChecks)
+			}
+			for i, check := range got.Checks {
+				if check.Status != tt.wantChecks[i] {
+					t.Errorf("healthService.Ready() check %s = %v, want %v", check.Name, check.Status, tt.wantChecks[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/helper"
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const minPasswordLength = 12
+
+var (
+	ErrInvalidCredentials = &UnauthorizedError{Message: "incorrect username or password"}
+	ErrOperatorNotFound   = &NotFoundError{Resource: "operator"}
+)
+
+// Hash compared against when the username does not exist, so that unknown
+// and known usernames take the same time to reject
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+type operatorService struct {
+	operatorRepository repository.OperatorRepository
+}
+
+type OperatorService interface {
+	Authenticate(ctx context.Context, username, password string) (*model.Operator, error)
+	Bootstrap(ctx context.Context) error
+	Create(ctx context.Context, req model.OperatorRequest) (*model.Operator, error)
+	Disable(ctx context.Context, username string) error
+	Get(ctx context.Context, id uint) (*model.Operator, error)
+	GetByUsername(ctx context.Context, username string) (*model.Operator, error)
+	RotatePassword(ctx context.Context, req model.OperatorRequest) error
+	SetRole(ctx context.Context, username, role string) error
+}
+
+func NewOperatorService(repository repository.OperatorRepository) OperatorService {
+	service := new(operatorService)
+	service.operatorRepository = repository
+	return service
+}
+
+// Check operator credentials
+func (o *operatorService) Authenticate(ctx context.Context, username, password string) (*model.Operator, error) {
+	operator, err := o.operatorRepository.GetByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(operator.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if operator.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+	return toOperatorModel(operator), nil
+}
+
+// Create the first operator from the environment when there is none yet
+func (o *operatorService) Bootstrap(ctx context.Context) error {
+	count, err := o.operatorRepository.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	username := config.GetEnvVariable("BOOTSTRAP_OPERATOR_USERNAME", "")
+	password := config.GetEnvSecret("BOOTSTRAP_OPERATOR_PASSWORD")
+	if username == "" || password == "" {
+		log.Warnln("No operators exist and BOOTSTRAP_OPERATOR_USERNAME/BOOTSTRAP_OPERATOR_PASSWORD are not set")
+		return nil
+	}
+
+	if _, err := o.Create(ctx, model.OperatorRequest{Username: username, Password: password, Role: model.RoleAdmin}); err != nil {
+		return err
+	}
+	log.Infof("Bootstrapped operator %s", username)
+	return nil
+}
+
+// Create new operator
+func (o *operatorService) Create(ctx context.Context, req model.OperatorRequest) (*model.Operator, error) {
+	if !helper.IsUsernameValid(req.Username) {
+		return nil, errors.New("invalid username")
+	}
+	if _, ok := model.RolePermissions[req.Role]; !ok {
+		return nil, errors.New("invalid role")
+	}
+	if _, err := o.operatorRepository.GetByUsername(ctx, req.Username); err == nil {
+		return nil, errors.New("operator already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := o.operatorRepository.Save(ctx, repository.Operator{
+		Username:          req.Username,
+		PasswordHash:      hash,
+		Role:              req.Role,
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOperatorModel(saved), nil
+}
+
+// Disable an operator so its credentials are no longer accepted
+func (o *operatorService) Disable(ctx context.Context, username string) error {
+	operator, err := o.getOperator(ctx, username)
+	if err != nil {
+		return err
+	}
+	operator.Disabled = true
+	_, err = o.operatorRepository.Update(ctx, *operator)
+	return err
+}
+
+// Get operator by ID
+func (o *operatorService) Get(ctx context.Context, id uint) (*model.Operator, error) {
+	operator, err := o.operatorRepository.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOperatorNotFound
+		}
+		return nil, err
+	}
+	return toOperatorModel(operator), nil
+}
+
+// Get operator by username
+func (o *operatorService) GetByUsername(ctx context.Context, username string) (*model.Operator, error) {
+	operator, err := o.getOperator(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return toOperatorModel(operator), nil
+}
+
+// Replace the password of an operator
+func (o *operatorService) RotatePassword(ctx context.Context, req model.OperatorRequest) error {
+	operator, err := o.getOperator(ctx, req.Username)
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+	operator.PasswordHash = hash
+	operator.PasswordChangedAt = time.Now()
+	_, err = o.operatorRepository.Update(ctx, *operator)
+	return err
+}
+
+// Change the role of an operator
+func (o *operatorService) SetRole(ctx context.Context, username, role string) error {
+	if _, ok := model.RolePermissions[role]; !ok {
+		return errors.New("invalid role")
+	}
+	operator, err := o.getOperator(ctx, username)
+	if err != nil {
+		return err
+	}
+	operator.Role = role
+	_, err = o.operatorRepository.Update(ctx, *operator)
+	return err
+}
+
+func (o *operatorService) getOperator(ctx context.Context, username string) (*repository.Operator, error) {
+	operator, err := o.operatorRepository.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOperatorNotFound
+		}
+		return nil, err
+	}
+	return operator, nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", errors.New("password must be at least 12 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func toOperatorModel(operator *repository.Operator) *model.Operator {
+	return &model.Operator{
+		ID:       operator.ID,
+		Username: operator.Username,
+		Role:     operator.Role,
+		Disabled: operator.Disabled,
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type MockOperatorStore struct {
+	operators map[string]repository.Operator
+}
+
+func newMockOperatorStore(operators ...repository.Operator) *MockOperatorStore {
+	store := &MockOperatorStore{operators: map[string]repository.Operator{}}
+	for _, operator := range operators {
+		store.operators[operator.Username] = operator
+	}
+	return store
+}
+
+func (o *MockOperatorStore) Count(ctx context.Context) (int64, error) {
+	return int64(len(o.operators)), nil
+}
+
+func (o *MockOperatorStore) Get(ctx context.Context, id uint) (*repository.Operator, error) {
+	for _, operator := range o.operators {
+		if operator.ID == id {
+			return &operator, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (o *MockOperatorStore) GetByUsername(ctx context.Context, username string) (*repository.Operator, error) {
+	operator, ok := o.operators[username]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &operator, nil
+}
+
+func (o *MockOperatorStore) Save(ctx context.Context, operator repository.Operator) (*repository.Operator, error) {
+	operator.ID = uint(len(o.operators) + 1)
+	o.operators[operator.Username] = operator
+	return &operator, nil
+}
+
+func (o *MockOperatorStore) Update(ctx context.Context, operator repository.Operator) (*repository.Operator, error) {
+	o.operators[operator.Username] = operator
+	return &operator, nil
+}
+
+func mockOperator(t *testing.T, username, password string, disabled bool) repository.Operator {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository.Operator{ID: 1, Username: username, PasswordHash: string(hash), Role: model.RoleAdmin, Disabled: disabled}
+}
+
+func Test_operatorService_Authenticate(t *testing.T) {
+	store := newMockOperatorStore(
+		mockOperator(t, "admin", "correct horse battery", false),
+		mockOperator(t, "former", "correct horse battery", true),
+	)
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{name: "should authenticate operator", username: "admin", password: "correct horse battery"},
+		{name: "should reject wrong password", username: "admin", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "should reject unknown operator", username: "nobody", password: "correct horse battery", wantErr: ErrInvalidCredentials},
+		{name: "should reject disabled operator", username: "former", password: "correct horse battery", wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &operatorService{operatorRepository: store}
+			got, err := o.Authenticate(context.Background(), tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("operatorService.Authenticate(context.Background(), ) error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && got.Username != tt.username {
+				t.Errorf("operatorService.Authenticate(context.Background(), ) = %v, want %v", got.Username, tt.username)
+			}
+		})
+	}
+}
+
+func Test_operatorService_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     model.OperatorRequest
+		wantErr bool
+	}{
+		{name: "should create operator", req: model.OperatorRequest{Username: "helpdesk", Password: "long enough password", Role: model.RoleHelpdesk}},
+		{name: "should reject existing operator", req: model.OperatorRequest{Username: "admin", Password: "long enough password", Role: model.RoleAdmin}, wantErr: true},
+		{name: "should reject short password", req: model.OperatorRequest{Username: "helpdesk", Password: "short", Role: model.RoleHelpdesk}, wantErr: true},
+		{name: "should reject invalid username", req: model.OperatorRequest{Username: "A", Password: "long enough password", Role: model.RoleHelpdesk}, wantErr: true},
+		{name: "should reject unknown role", req: model.OperatorRequest{Username: "helpdesk", Password: "long enough password", Role: "root"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockOperatorStore(mockOperator(t, "admin", "correct horse battery", false))
+			o := &operatorService{operatorRepository: store}
+			_, err := o.Create(context.Background(), tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("operatorService.Create(context.Background(), ) error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if _, err := o.Authenticate(context.Background(), tt.req.Username, tt.req.Password); err != nil {
+					t.Errorf("operatorService.Authenticate(context.Background(), ) after create error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_operatorService_RotatePasswordRoleAndDisable(t *testing.T) {
+	store := newMockOperatorStore(mockOperator(t, "admin", "correct horse battery", false))
+	o := &operatorService{operatorRepository: store}
+
+	if err := o.RotatePassword(context.Background(), model.OperatorRequest{Username: "admin", Password: "a brand new password"}); err != nil {
+		t.Fatalf("operatorService.RotatePassword(context.Background(), ) error = %v", err)
+	}
+	if _, err := o.Authenticate(context.Background(), "admin", "correct horse battery"); err == nil {
+		t.Errorf("operatorService.Authenticate(context.Background(), ) accepted the old password")
+	}
+	if _, err := o.Authenticate(context.Background(), "admin", "a brand new password"); err != nil {
+		t.Errorf("operatorService.Authenticate(context.Background(), ) error = %v", err)
+	}
+
+	if err := o.SetRole(context.Background(), "admin", model.RoleHelpdesk); err != nil {
+		t.Fatalf("operatorService.SetRole(context.Background(), ) error = %v", err)
+	}
+	if got, _ := o.Authenticate(context.Background(), "admin", "a brand new password"); got == nil || got.Role != model.RoleHelpdesk {
+		t.Errorf("operatorService.Authenticate(context.Background(), ) = %+v, want role %v", got, model.RoleHelpdesk)
+	}
+	if err := o.SetRole(context.Background(), "admin", "root"); err == nil {
+		t.Errorf("operatorService.SetRole(context.Background(), ) accepted an unknown role")
+	}
+
+	if err := o.Disable(context.Background(), "admin"); err != nil {
+		t.Fatalf("operatorService.Disable(context.Background(), ) error = %v", err)
+	}
+	if _, err := o.Authenticate(context.Background(), "admin", "a brand new password"); err == nil {
+		t.Errorf("operatorService.Authenticate(context.Background(), ) accepted a disabled operator")
+	}
+	if err := o.Disable(context.Background(), "nobody"); !errors.Is(err, ErrOperatorNotFound) {
+		t.Errorf("operatorService.Disable(context.Background(), ) error = %v, want %v", err, ErrOperatorNotFound)
+	}
+}
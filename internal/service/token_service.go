@@ -0,0 +1,224 @@
+package service
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidToken = &UnauthorizedError{Message: "invalid or expired token"}
+
+type tokenService struct {
+	operatorService        OperatorService
+	refreshTokenRepository repository.RefreshTokenRepository
+	keys                   *config.SigningKeys
+}
+
+type TokenService interface {
+	Login(ctx context.Context, req model.LoginRequest) (*model.Token, error)
+	Logout(ctx context.Context, req model.RefreshRequest) error
+	Refresh(ctx context.Context, req model.RefreshRequest) (*model.Token, error)
+	VerifyAccessToken(ctx context.Context, token string) (*model.Operator, error)
+}
+
+// Claims carried by access tokens
+type accessClaims struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func NewTokenService(operatorService OperatorService, repository repository.RefreshTokenRepository, keys *config.SigningKeys) TokenService {
+	service := new(tokenService)
+	service.operatorService = operatorService
+	service.refreshTokenRepository = repository
+	service.keys = keys
+	return service
+}
+
+// Exchange operator credentials for an access and refresh token
+func (t *tokenService) Login(ctx context.Context, req model.LoginRequest) (*model.Token, error) {
+	operator, err := t.operatorService.Authenticate(ctx, req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(ctx, operator, family)
+}
+
+// Revoke a refresh token
+func (t *tokenService) Logout(ctx context.Context, req model.RefreshRequest) error {
+	token, err := t.refreshTokenRepository.GetByHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	_, err = t.refreshTokenRepository.Revoke(ctx, token.ID)
+	return err
+}
+
+// Exchange a refresh token for a new access and refresh token. The presented
+// refresh token is revoked so that each one can only be used once; using it
+// again, even concurrently, revokes every token issued from the same login.
+func (t *tokenService) Refresh(ctx context.Context, req model.RefreshRequest) (*model.Token, error) {
+	token, err := t.refreshTokenRepository.GetByHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, t.reused(ctx, token)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	operator, err := t.operatorService.Get(ctx, token.OperatorID)
+	if err != nil {
+		if errors.Is(err, ErrOperatorNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if operator.Disabled {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := t.refreshTokenRepository.Revoke(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// Another request rotated the token first
+		return nil, t.reused(ctx, token)
+	}
+	return t.issue(ctx, operator, token.Family())
+}
+
+// Revoke the family of a refresh token presented after it was revoked. The
+// token may have been stolen, and there is no telling which holder is which.
+func (t *tokenService) reused(ctx context.Context, token *repository.RefreshToken) error {
+	if err := t.refreshTokenRepository.RevokeFamily(ctx, token.Family()); err != nil {
+		return err
+	}
+	return ErrInvalidToken
+}
+
+// Validate a signed access token and return the operator it was issued to,
+// as currently stored
+func (t *tokenService) VerifyAccessToken(ctx context.Context, token string) (*model.Operator, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := t.keys.VerificationKey(kid)
+		if !ok {
+			return nil, errors.New("unknown kid")
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{t.keys.Method.Alg()}),
+		jwt.WithIssuer(t.keys.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// The claims may be out of date: the operator could since have been
+	// disabled or had their role changed
+	operator, err := t.operatorService.Get(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrOperatorNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if operator.Disabled {
+		return nil, ErrInvalidToken
+	}
+	return operator, nil
+}
+
+func (t *tokenService) issue(ctx context.Context, operator *model.Operator, family string) (*model.Token, error) {
+	now := time.Now()
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	claims := accessClaims{
+		Username: operator.Username,
+		Role:     operator.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.keys.Issuer,
+			Subject:   strconv.FormatUint(uint64(operator.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.keys.AccessTTL)),
+			ID:        jti,
+		},
+	}
+	kid, key := t.keys.SigningKey()
+	accessToken := jwt.NewWithClaims(t.keys.Method, claims)
+	accessToken.Header["kid"] = kid
+	signed, err := accessToken.SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.refreshTokenRepository.Save(ctx, repository.RefreshToken{
+		OperatorID: operator.ID,
+		TokenHash:  hashToken(refreshToken),
+		FamilyID:   &family,
+		ExpiresAt:  now.Add(t.keys.RefreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &model.Token{
+		AccessToken:  signed,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.keys.AccessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Refresh tokens are stored as SHA-256 hashes so a database leak does not
+// expose usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
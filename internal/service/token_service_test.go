@@ -0,0 +1,252 @@
+package service
+
+import (
+	"atmail/internal/config"
+	"atmail/internal/model"
+	"atmail/internal/repository"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+type MockRefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]repository.RefreshToken
+}
+
+func (r *MockRefreshTokenStore) GetByHash(ctx context.Context, hash string) (*repository.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[hash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+func (r *MockRefreshTokenStore) Revoke(ctx context.Context, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, token := range r.tokens {
+		if token.ID == id && token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			r.tokens[hash] = token
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRefreshTokenStore) RevokeFamily(ctx context.Context, family string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, token := range r.tokens {
+		if token.Family() == family && token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			r.tokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (r *MockRefreshTokenStore) Save(ctx context.Context, token repository.RefreshToken) (*repository.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens[token.TokenHash] = token
+	return &token, nil
+}
+
+func newTestTokenService(t *testing.T, keys *config.SigningKeys) *tokenService {
+	store := newMockOperatorStore(mockOperator(t, "admin", "correct horse battery", false))
+	return &tokenService{
+		operatorService:        &operatorService{operatorRepository: store},
+		refreshTokenRepository: &MockRefreshTokenStore{tokens: map[string]repository.RefreshToken{}},
+		keys:                   keys,
+	}
+}
+
+func hmacKeys(t *testing.T, active string) *config.SigningKeys {
+	keys, err := config.NewSigningKeys(jwt.SigningMethodHS256, active, map[string]interface{}{
+		"old": []byte("old secret"),
+		"new": []byte("new secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func Test_tokenService_LoginAndVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKeys, err := config.NewSigningKeys(jwt.SigningMethodEdDSA, "ed", map[string]interface{}{"ed": edKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     *config.SigningKeys
+		password string
+		wantErr  error
+	}{
+		{name: "should login with HS256", keys: hmacKeys(t, "new"), password: "correct horse battery"},
+		{name: "should login with EdDSA", keys: edKeys, password: "correct horse battery"},
+		{name: "should reject wrong password", keys: hmacKeys(t, "new"), password: "wrong", wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestTokenService(t, tt.keys)
+			token, err := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: tt.password})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("tokenService.Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			operator, err := s.VerifyAccessToken(context.Background(), token.AccessToken)
+			if err != nil {
+				t.Fatalf("tokenService.VerifyAccessToken() error = %v", err)
+			}
+			if operator.ID != 1 || operator.Username != "admin" || operator.Role != model.RoleAdmin {
+				t.Errorf("tokenService.VerifyAccessToken() = %+v", operator)
+			}
+		})
+	}
+}
+
+func Test_tokenService_KeyRotation(t *testing.T) {
+	s := newTestTokenService(t, hmacKeys(t, "old"))
+	token, err := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens signed with the previous key stay valid after the active key changes
+	s.keys = hmacKeys(t, "new")
+	if _, err := s.VerifyAccessToken(context.Background(), token.AccessToken); err != nil {
+		t.Errorf("tokenService.VerifyAccessToken() error = %v", err)
+	}
+
+	// Tokens signed with a key that was removed are rejected
+	keys, err := config.NewSigningKeys(jwt.SigningMethodHS256, "new", map[string]interface{}{"new": []byte("new secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keys = keys
+	if _, err := s.VerifyAccessToken(context.Background(), token.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.VerifyAccessToken(context.Background(), "not a token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func Test_tokenService_VerifyUsesCurrentOperator(t *testing.T) {
+	s := newTestTokenService(t, hmacKeys(t, "new"))
+	token, err := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A downgraded role applies to tokens issued before the change
+	if err := s.operatorService.SetRole(context.Background(), "admin", model.RoleHelpdesk); err != nil {
+		t.Fatal(err)
+	}
+	operator, err := s.VerifyAccessToken(context.Background(), token.AccessToken)
+	if err != nil {
+		t.Fatalf("tokenService.VerifyAccessToken() error = %v", err)
+	}
+	if operator.Role != model.RoleHelpdesk {
+		t.Errorf("tokenService.VerifyAccessToken() role = %q, want %q", operator.Role, model.RoleHelpdesk)
+	}
+
+	// Disabled operators' tokens stop working at once
+	if err := s.operatorService.Disable(context.Background(), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.VerifyAccessToken(context.Background(), token.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func Test_tokenService_RefreshAndLogout(t *testing.T) {
+	s := newTestTokenService(t, hmacKeys(t, "new"))
+	first, err := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("tokenService.Refresh() error = %v", err)
+	}
+	if _, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: first.RefreshToken}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.Refresh() reused token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	if err := s.Logout(context.Background(), model.RefreshRequest{RefreshToken: second.RefreshToken}); err != nil {
+		t.Fatalf("tokenService.Logout() error = %v", err)
+	}
+	if _, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: second.RefreshToken}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.Refresh() revoked token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if err := s.Logout(context.Background(), model.RefreshRequest{RefreshToken: "unknown"}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.Logout() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func Test_tokenService_RefreshReuseRevokesFamily(t *testing.T) {
+	s := newTestTokenService(t, hmacKeys(t, "new"))
+	first, err := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := s.Login(context.Background(), model.LoginRequest{Username: "admin", Password: "correct horse battery"})
+
+	// Concurrent refreshes with one token: only one gets new tokens
+	var wg sync.WaitGroup
+	results := make(chan *model.Token, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: first.RefreshToken})
+			if err == nil {
+				results <- token
+			} else if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("tokenService.Refresh() error = %v, want %v", err, ErrInvalidToken)
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	var issued []*model.Token
+	for token := range results {
+		issued = append(issued, token)
+	}
+	if len(issued) != 1 {
+		t.Fatalf("tokenService.Refresh() succeeded %d times, want 1", len(issued))
+	}
+
+	// The losers revoked the family, so the winner's token is dead too, but
+	// other logins are not
+	if _, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: issued[0].RefreshToken}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tokenService.Refresh() family token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.Refresh(context.Background(), model.RefreshRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Errorf("tokenService.Refresh() other login error = %v", err)
+	}
+}
@@ -1,185 +1,613 @@
 package service
 
 import (
+	"atmail/internal/config"
 	"atmail/internal/helper"
+	"atmail/internal/jsonpatch"
+	"atmail/internal/metrics"
 	"atmail/internal/model"
 	"atmail/internal/repository"
+	"atmail/internal/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
+	"math"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// Columns users can be sorted by
+var sortableFields = map[string]bool{
+	"id":       true,
+	"username": true,
+	"email":    true,
+	"age":      true,
+}
+
+// Field errors found by user validation. Their codes double as the reason
+// label of the validation failure metric.
+var (
+	errEmailRequired    = FieldError{Field: "email", Code: "email_required", Message: "email is required"}
+	errInvalidEmail     = FieldError{Field: "email", Code: "email_invalid", Message: "invalid email"}
+	errEmailExists      = FieldError{Field: "email", Code: "email_exists", Message: "email already exists"}
+	errUsernameRequired = FieldError{Field: "username", Code: "username_required", Message: "username is required"}
+	errInvalidUsername  = FieldError{Field: "username", Code: "username_invalid", Message: "invalid username"}
+	errUsernameExists   = FieldError{Field: "username", Code: "username_exists", Message: "username already exists"}
+	errInvalidAge       = FieldError{Field: "age", Code: "age_invalid", Message: "invalid age"}
+	errVersionConflict  = FieldError{Field: "version", Code: "version_conflict", Message: "user was changed by another request, try again"}
+	errUserNotDeleted   = FieldError{Field: "deleted_at", Code: "user_not_deleted", Message: "user is not deleted"}
+	errUserNotFound     = &NotFoundError{Resource: "user"}
+	errUserChanged      = &PreconditionFailedError{Message: "user has changed since it was read"}
+)
+
 type userService struct {
 	userRepository repository.UserRepository
+	deletion       config.UserDeletionConfig
 }
 
 type UserService interface {
-	Delete(id uint) error
-	Get(id uint) (*model.User, int, error)
-	GetAll() (*[]model.User, error)
-	Save(req model.UserRequest) (resp *model.User, err error)
-	Update(req model.User) (*model.User, error)
-	ValidateNewUser(req model.UserRequest) error
-	ValidateExistingUser(req model.User) (int, error)
-	ValidateID(id uint) (int, error)
+	ChangeMarker(ctx context.Context) (*model.ChangeMarker, error)
+	Delete(ctx context.Context, id uint, version *uint) error
+	Get(ctx context.Context, id uint) (*model.User, error)
+	GetAll(ctx context.Context) (*[]model.User, error)
+	List(ctx context.Context, query model.UserQuery) (*model.UserList, error)
+	Patch(ctx context.Context, id uint, patch model.UserPatch, version *uint) (*model.User, error)
+	Purge(ctx context.Context, id uint, version *uint) error
+	Restore(ctx context.Context, id uint, version *uint) (*model.User, error)
+	Save(ctx context.Context, req model.UserRequest) (resp *model.User, err error)
+	Update(ctx context.Context, id uint, req model.UserUpdateRequest, version *uint) (*model.User, error)
+	ValidateNewUser(ctx context.Context, req model.UserRequest) error
+	ValidateExistingUser(ctx context.Context, id uint, req model.UserUpdateRequest) error
+	ValidateID(ctx context.Context, id uint) error
+	ValidateQuery(ctx context.Context, query model.UserQuery) error
 }
 
-func NewUserService(repository repository.UserRepository) UserService {
+func NewUserService(repository repository.UserRepository, deletion config.UserDeletionConfig) UserService {
 	service := new(userService)
 	service.userRepository = repository
+	service.deletion = deletion
 	return service
 }
 
 // Get user by ID
-func (u *userService) Get(id uint) (*model.User, int, error) {
-	user, err := u.userRepository.Get(id)
+func (u *userService) Get(ctx context.Context, id uint) (*model.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Get")
+	defer span.End()
+	user, err := u.userRepository.Get(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, http.StatusNotFound, errors.New("user not found")
+			return nil, errUserNotFound
 		}
-		return nil, http.StatusBadRequest, err
+		return nil, internalError(err)
 	}
 
-	return user, http.StatusOK, nil
+	return user, nil
 }
 
 // Get all users
-func (u *userService) GetAll() (*[]model.User, error) {
-	users, err := u.userRepository.GetAll()
+func (u *userService) GetAll(ctx context.Context) (*[]model.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.GetAll")
+	defer span.End()
+	users, err := u.userRepository.GetAll(ctx)
 	if err != nil {
-		return nil, err
+		return nil, internalError(err)
 	}
 	return users, nil
 }
 
+// Version and time of the latest change to any user
+func (u *userService) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.ChangeMarker")
+	defer span.End()
+	marker, err := u.userRepository.ChangeMarker(ctx)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return marker, nil
+}
+
+// List a page of users matching the query
+func (u *userService) List(ctx context.Context, query model.UserQuery) (*model.UserList, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.List")
+	defer span.End()
+	opts, err := u.listOptions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to find out if there is a next page
+	limit := opts.Limit
+	opts.Limit++
+	users, total, err := u.userRepository.List(ctx, *opts)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	list := &model.UserList{Items: []model.User{}, Total: total}
+	if users != nil {
+		list.Items = *users
+	}
+	if len(list.Items) > limit {
+		list.Items = list.Items[:limit]
+		last := list.Items[limit-1]
+		next, err := helper.EncodeCursor(helper.Cursor{
+			Sort:  cursorSort(opts),
+			Value: sortValue(last, opts.SortField),
+			ID:    last.ID,
+		})
+		if err != nil {
+			return nil, internalError(err)
+		}
+		list.NextCursor = next
+	}
+	return list, nil
+}
+
+// validate the list query and convert it to repository options
+func (u *userService) listOptions(query model.UserQuery) (*repository.UserListOptions, error) {
+	opts := repository.UserListOptions{
+		Limit:          defaultListLimit,
+		SortField:      "id",
+		UsernamePrefix: query.UsernamePrefix,
+		EmailDomain:    query.EmailDomain,
+		MinAge:         query.MinAge,
+		MaxAge:         query.MaxAge,
+		CreatedAfter:   query.CreatedAfter,
+		UpdatedBefore:  query.UpdatedBefore,
+		IncludeDeleted: query.IncludeDeleted,
+	}
+
+	if query.Limit < 0 || query.Limit > maxListLimit {
+		return nil, invalidField("limit", "limit_out_of_range", fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	if query.Limit > 0 {
+		opts.Limit = query.Limit
+	}
+
+	if query.Sort != "" {
+		if !sortableFields[query.Sort] {
+			return nil, invalidField("sort", "sort_invalid", "invalid sort field")
+		}
+		opts.SortField = query.Sort
+	}
+
+	switch strings.ToLower(query.Order) {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return nil, invalidField("order", "order_invalid", "invalid sort order")
+	}
+
+	if query.MinAge != nil && query.MaxAge != nil && *query.MinAge > *query.MaxAge {
+		return nil, invalidField("min_age", "age_range_invalid", "min_age must not be greater than max_age")
+	}
+	// Users are never updated before they are created
+	if query.CreatedAfter != nil && query.UpdatedBefore != nil && !query.CreatedAfter.Before(*query.UpdatedBefore) {
+		return nil, invalidField("created_after", "time_range_invalid", "created_after must be before updated_before")
+	}
+
+	if query.Cursor != "" {
+		cursor, err := helper.DecodeCursor(query.Cursor)
+		if err != nil {
+			return nil, invalidField("cursor", "cursor_invalid", err.Error())
+		}
+		if cursor.Sort != cursorSort(&opts) {
+			return nil, invalidField("cursor", "cursor_sort_mismatch", "cursor does not match the requested sort")
+		}
+		value, ok := cursorValue(opts.SortField, cursor.Value)
+		if !ok {
+			return nil, invalidField("cursor", "cursor_invalid", "invalid cursor")
+		}
+		opts.AfterValue = value
+		opts.AfterID = cursor.ID
+	}
+	return &opts, nil
+}
+
+func cursorSort(opts *repository.UserListOptions) string {
+	if opts.Descending {
+		return opts.SortField + ":desc"
+	}
+	return opts.SortField + ":asc"
+}
+
+// Value of a decoded cursor as the type of field, false when it has another
+// type. Sorting by id only uses the cursor's ID.
+func cursorValue(field string, value interface{}) (interface{}, bool) {
+	switch field {
+	case "username", "email":
+		s, ok := value.(string)
+		return s, ok
+	case "age":
+		// JSON numbers decode as float64
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, false
+		}
+		return int(f), true
+	default:
+		return nil, true
+	}
+}
+
+func sortValue(user model.User, field string) interface{} {
+	switch field {
+	case "username":
+		return user.Username
+	case "email":
+		return user.Email
+	case "age":
+		return user.Age
+	default:
+		return user.ID
+	}
+}
+
 // Create new user
-func (u *userService) Save(req model.UserRequest) (user *model.User, err error) {
+func (u *userService) Save(ctx context.Context, req model.UserRequest) (user *model.User, err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Save")
+	defer span.End()
 	var r repository.User
 	r.Username = req.Username
 	r.Email = req.Email
 	r.Age = req.Age
+	r.CreatedBy = author(ctx)
+	r.UpdatedBy = r.CreatedBy
 
-	updated, err := u.userRepository.Save(r)
+	updated, err := u.userRepository.Save(ctx, r)
 	if err != nil {
-		return nil, err
+		return nil, repositoryError(err)
 	}
 	return updated, nil
 }
 
-// Validate requests for new users
-func (u *userService) ValidateNewUser(req model.UserRequest) error {
-	var id *uint
-	if err := u.validateEmail(req.Email, id); err != nil {
+// Validate requests for new users, reporting every invalid field at once
+func (u *userService) ValidateNewUser(ctx context.Context, req model.UserRequest) (err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.ValidateNewUser")
+	defer span.End()
+	defer func() { recordValidationFailure(err) }()
+	return u.validateFields(ctx, nil, nil, req.Email, req.Username, req.Age)
+}
+
+// Validate requests for existing users, reporting every invalid field at once
+func (u *userService) ValidateExistingUser(ctx context.Context, id uint, req model.UserUpdateRequest) (err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.ValidateExistingUser")
+	defer span.End()
+	defer func() { recordValidationFailure(err) }()
+	if err := u.validateID(ctx, id); err != nil {
 		return err
 	}
-	if err := u.validateUsername(req.Username, id); err != nil {
+	return u.validateFields(ctx, &id, nil, req.Email, req.Username, req.Age)
+}
+
+// Check every field of a user. Taken emails and usernames are a conflict
+// when nothing else is wrong, and listed with the other errors otherwise.
+// Values equal to those of current, when given, are not checked for
+// uniqueness.
+func (u *userService) validateFields(ctx context.Context, id *uint, current *repository.User, email, username string, age int) error {
+	var errs []FieldError
+	emailErr, err := u.validateEmail(ctx, email, id, current == nil || current.Email != email)
+	if err != nil {
 		return err
 	}
-	if !helper.IsAgeValid(req.Age) {
-		return errors.New("invalid age")
+	usernameErr, err := u.validateUsername(ctx, username, id, current == nil || current.Username != username)
+	if err != nil {
+		return err
+	}
+	for _, fieldErr := range []*FieldError{emailErr, usernameErr} {
+		if fieldErr != nil {
+			errs = append(errs, *fieldErr)
+		}
+	}
+	if !helper.IsAgeValid(age) {
+		errs = append(errs, errInvalidAge)
 	}
-	return nil
-}
 
-// Validate requests for existing users
-func (u *userService) ValidateExistingUser(req model.User) (int, error) {
-	statusCode, err := u.validateID(req.ID)
-	if err != nil {
-		return statusCode, err
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, e := range errs {
+		if e != errEmailExists && e != errUsernameExists {
+			return &ValidationError{Errors: errs}
+		}
 	}
-	if err := u.validateEmail(req.Email, &req.ID); err != nil {
-		return http.StatusBadRequest, err
+	return &ConflictError{Errors: errs}
+}
+
+// Pass conflicts from the unique indexes through and treat anything else as
+// an internal failure
+func repositoryError(err error) error {
+	var conflict *repository.ConflictError
+	if errors.As(err, &conflict) {
+		if conflict.Field == "email" {
+			return &ConflictError{Errors: []FieldError{errEmailExists}}
+		}
+		return &ConflictError{Errors: []FieldError{errUsernameExists}}
 	}
-	if err := u.validateUsername(req.Username, &req.ID); err != nil {
-		return http.StatusBadRequest, err
+	return internalError(err)
+}
+
+func recordValidationFailure(err error) {
+	var (
+		validation *ValidationError
+		conflict   *ConflictError
+		notFound   *NotFoundError
+	)
+	switch {
+	case errors.As(err, &validation):
+		recordFieldErrors(validation.Errors)
+	case errors.As(err, &conflict):
+		recordFieldErrors(conflict.Errors)
+	case errors.As(err, &notFound):
+		metrics.ValidationFailures.WithLabelValues("not_found").Inc()
 	}
-	if !helper.IsAgeValid(req.Age) {
-		return http.StatusBadRequest, errors.New("invalid age")
+}
+
+func recordFieldErrors(errs []FieldError) {
+	for _, e := range errs {
+		metrics.ValidationFailures.WithLabelValues(e.Code).Inc()
 	}
-	return http.StatusOK, nil
 }
 
 // validate if ID exists in the database
-func (u *userService) validateID(id uint) (int, error) {
-	_, err := u.userRepository.Get(id)
+func (u *userService) validateID(ctx context.Context, id uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.validateID")
+	defer span.End()
+	_, err := u.userRepository.Get(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return http.StatusNotFound, errors.New("no record found")
+			return errUserNotFound
 		}
-		return http.StatusBadRequest, err
+		return internalError(err)
 	}
-	return http.StatusOK, nil
+	return nil
 }
 
-// validate if email exists in the database
-func (u *userService) validateEmail(email string, id *uint) error {
+// validate the email format and, if checkUnique, that no other user has it.
+// The error is only set when the check itself failed.
+func (u *userService) validateEmail(ctx context.Context, email string, id *uint, checkUnique bool) (*FieldError, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.validateEmail")
+	defer span.End()
 	if len(email) == 0 {
-		return errors.New("email is required")
+		return &errEmailRequired, nil
 	}
 	if !helper.IsEmailValid(email) {
-		return errors.New("invalid email")
+		return &errInvalidEmail, nil
+	}
+	if !checkUnique {
+		return nil, nil
 	}
 
-	isUnique, err := u.userRepository.IsEmailUnique(id, email)
+	isUnique, err := u.userRepository.IsEmailUnique(ctx, id, email, u.deletion.ReserveValues)
 	if err != nil {
-		return err
+		return nil, internalError(err)
 	}
 	if !isUnique {
-		return errors.New("email already exists")
+		return &errEmailExists, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
-// validate if username exists in the database
-func (u *userService) validateUsername(username string, id *uint) error {
+// validate the username format and, if checkUnique, that no other user has
+// it. The error is only set when the check itself failed.
+func (u *userService) validateUsername(ctx context.Context, username string, id *uint, checkUnique bool) (*FieldError, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.validateUsername")
+	defer span.End()
 	if len(username) == 0 {
-		return errors.New("username is required")
+		return &errUsernameRequired, nil
 	}
 	if !helper.IsUsernameValid(username) {
-		return errors.New("invalid username")
+		return &errInvalidUsername, nil
+	}
+	if !checkUnique {
+		return nil, nil
 	}
 
-	isUnique, err := u.userRepository.IsUsernameUnique(id, username)
+	isUnique, err := u.userRepository.IsUsernameUnique(ctx, id, username, u.deletion.ReserveValues)
 	if err != nil {
-		return err
+		return nil, internalError(err)
 	}
 	if !isUnique {
-		return errors.New("username already exists")
+		return &errUsernameExists, nil
 	}
 
-	return nil
+	return nil, nil
+}
+
+// Update changes. When version is given the user must still have it.
+func (u *userService) Update(ctx context.Context, id uint, req model.UserUpdateRequest, version *uint) (*model.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Update")
+	defer span.End()
+	user, err := u.userForChange(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	user.Username = req.Username
+	user.Email = req.Email
+	user.Age = req.Age
+	user.UpdatedBy = author(ctx)
+	updated, err := u.userRepository.Update(ctx, *user)
+	if err != nil {
+		return nil, changeError(err, version)
+	}
+	return updated, nil
 }
 
-// Update changes
-func (u *userService) Update(req model.User) (*model.User, error) {
-	user, err := u.userRepository.GetUser(req.ID)
+// Reference to the principal making a change, nil when the request was not
+// authenticated
+func author(ctx context.Context) *string {
+	principal := model.PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil
+	}
+	ref := principal.Ref()
+	return &ref
+}
+
+// Load a user about to be changed, checking it has version when one is given
+func (u *userService) userForChange(ctx context.Context, id uint, version *uint) (*repository.User, error) {
+	user, err := u.userRepository.GetUser(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("no record found")
+			return nil, errUserNotFound
 		}
+		return nil, internalError(err)
+	}
+	if version != nil && user.Version != *version {
+		return nil, errUserChanged
+	}
+	return user, nil
+}
+
+// Error for a failed conditional write. The user changed after it was read:
+// the caller's precondition fails if it gave one, otherwise the request
+// conflicts with the other write.
+func changeError(err error, version *uint) error {
+	switch {
+	case errors.Is(err, repository.ErrVersionMismatch) && version != nil:
+		return errUserChanged
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return &ConflictError{Errors: []FieldError{errVersionConflict}}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errUserNotFound
+	default:
+		return repositoryError(err)
+	}
+}
+
+// Apply a JSON Merge Patch or JSON Patch to a user. The patched user is
+// validated as a whole, but only changed emails and usernames are checked for
+// uniqueness. When version is given the user must still have it.
+func (u *userService) Patch(ctx context.Context, id uint, patch model.UserPatch, version *uint) (*model.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Patch")
+	defer span.End()
+	user, err := u.userForChange(ctx, id, version)
+	if err != nil {
 		return nil, err
 	}
+
+	req, err := applyUserPatch(model.UserUpdateRequest{Username: user.Username, Email: user.Email, Age: user.Age}, patch)
+	if err == nil {
+		err = u.validateFields(ctx, &id, user, req.Email, req.Username, req.Age)
+	}
+	if err != nil {
+		recordValidationFailure(err)
+		return nil, err
+	}
+
 	user.Username = req.Username
 	user.Email = req.Email
 	user.Age = req.Age
-	updated, err := u.userRepository.Update(*user)
+	user.UpdatedBy = author(ctx)
+	updated, err := u.userRepository.Update(ctx, *user)
 	if err != nil {
-		return nil, err
+		return nil, changeError(err, version)
 	}
 	return updated, nil
 }
 
-// Delete a user
-func (u *userService) Delete(id uint) error {
-	if err := u.userRepository.Delete(id); err != nil {
-		return err
+// The editable fields of a user after applying patch to them
+func applyUserPatch(current model.UserUpdateRequest, patch model.UserPatch) (model.UserUpdateRequest, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return current, internalError(err)
+	}
+	switch patch.MediaType {
+	case jsonpatch.MergePatchType:
+		doc, err = jsonpatch.MergePatch(doc, patch.Document)
+	case jsonpatch.JSONPatchType:
+		doc, err = jsonpatch.Apply(doc, patch.Document)
+	default:
+		return current, invalidField("patch", "patch_type_unsupported", "unsupported patch media type "+patch.MediaType)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return current, &ConflictError{Errors: []FieldError{{Field: "patch", Code: "patch_test_failed", Message: err.Error()}}}
+	}
+	if err != nil {
+		return current, invalidField("patch", "patch_invalid", err.Error())
+	}
+
+	var patched model.UserUpdateRequest
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return current, invalidField(typeErr.Field, "type_invalid", typeErr.Field+" must be "+helper.JSONTypeName(typeErr.Type))
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return current, invalidField(field, "field_unknown", "unknown field "+field)
+		default:
+			return current, invalidField("patch", "patch_invalid", "patched user must be a JSON object")
+		}
+	}
+	return patched, nil
+}
+
+// Soft delete a user, who can be restored until purged. When version is
+// given the user must still have it.
+func (u *userService) Delete(ctx context.Context, id uint, version *uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Delete")
+	defer span.End()
+	if version != nil {
+		if _, err := u.userForChange(ctx, id, version); err != nil {
+			return err
+		}
+	}
+	if err := u.userRepository.Delete(ctx, id, version); err != nil {
+		return changeError(err, version)
+	}
+	return nil
+}
+
+// Undo the deletion of a user. When version is given the user must still
+// have it.
+func (u *userService) Restore(ctx context.Context, id uint, version *uint) (*model.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Restore")
+	defer span.End()
+	restored, err := u.userRepository.Restore(ctx, id, version, author(ctx))
+	if errors.Is(err, repository.ErrNotDeleted) {
+		return nil, &ConflictError{Errors: []FieldError{errUserNotDeleted}}
+	}
+	if err != nil {
+		return nil, changeError(err, version)
+	}
+	return restored, nil
+}
+
+// Permanently remove a user, deleted or not. When version is given the user
+// must still have it.
+func (u *userService) Purge(ctx context.Context, id uint, version *uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Purge")
+	defer span.End()
+	if err := u.userRepository.Purge(ctx, id, version); err != nil {
+		return changeError(err, version)
 	}
 	return nil
 }
 
 // Validate if ID exists in the DB
-func (u *userService) ValidateID(id uint) (int, error) {
-	return u.validateID(id)
+func (u *userService) ValidateID(ctx context.Context, id uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.ValidateID")
+	defer span.End()
+	return u.validateID(ctx, id)
+}
+
+// Validate a list query without running it
+func (u *userService) ValidateQuery(ctx context.Context, query model.UserQuery) error {
+	_, span := tracing.Tracer.Start(ctx, "UserService.ValidateQuery")
+	defer span.End()
+	_, err := u.listOptions(query)
+	return err
 }
@@ -1,17 +1,24 @@
 package service
 
 import (
+	"atmail/internal/config"
+	"atmail/internal/helper"
 	"atmail/internal/model"
 	"atmail/internal/repository"
+	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type MockUser struct{}
 type MockUserNotFound struct{}
 
-func (u *MockUser) Get(id uint) (*model.User, error) {
+func (u *MockUser) Get(ctx context.Context, id uint) (*model.User, error) {
 	return &model.User{
 		ID:       id,
 		Username: "username1",
@@ -20,11 +27,11 @@ func (u *MockUser) Get(id uint) (*model.User, error) {
 	}, nil
 }
 
-func (u *MockUserNotFound) Get(id uint) (*model.User, error) {
+func (u *MockUserNotFound) Get(ctx context.Context, id uint) (*model.User, error) {
 	return nil, errors.New("record not found")
 }
 
-func (u *MockUser) GetAll() (*[]model.User, error) {
+func (u *MockUser) GetAll(ctx context.Context) (*[]model.User, error) {
 	return &[]model.User{
 		{
 			ID:       1,
@@ -41,11 +48,27 @@ func (u *MockUser) GetAll() (*[]model.User, error) {
 	}, nil
 }
 
-func (u *MockUserNotFound) GetAll() (*[]model.User, error) {
+func (u *MockUserNotFound) GetAll(ctx context.Context) (*[]model.User, error) {
 	return nil, errors.New("no record found")
 }
 
-func (u *MockUser) GetUser(id uint) (*repository.User, error) {
+func (u *MockUser) List(ctx context.Context, opts repository.UserListOptions) (*[]model.User, int64, error) {
+	users := []model.User{
+		{ID: 1, Username: "username1", Email: "email1", Age: 12},
+		{ID: 2, Username: "username2", Email: "email2", Age: 34},
+		{ID: 3, Username: "username3", Email: "email3", Age: 56},
+	}
+	if opts.Limit < len(users) {
+		users = users[:opts.Limit]
+	}
+	return &users, 3, nil
+}
+
+func (u *MockUserNotFound) List(ctx context.Context, opts repository.UserListOptions) (*[]model.User, int64, error) {
+	return nil, 0, errors.New("no record found")
+}
+
+func (u *MockUser) GetUser(ctx context.Context, id uint) (*repository.User, error) {
 	return &repository.User{
 		ID:       id,
 		Username: "username1",
@@ -54,35 +77,51 @@ func (u *MockUser) GetUser(id uint) (*repository.User, error) {
 	}, nil
 }
 
-func (u *MockUserNotFound) GetUser(id uint) (*repository.User, error) {
+func (u *MockUserNotFound) GetUser(ctx context.Context, id uint) (*repository.User, error) {
 	return nil, errors.New("no record found")
 }
 
-func (u *MockUser) Delete(id uint) error {
+func (u *MockUser) Delete(ctx context.Context, id uint, version *uint) error {
 	return nil
 }
 
-func (u *MockUserNotFound) Delete(id uint) error {
+func (u *MockUserNotFound) Delete(ctx context.Context, id uint, version *uint) error {
 	return errors.New("user not found")
 }
 
-func (u *MockUser) IsEmailUnique(id *uint, email string) (bool, error) {
+func (u *MockUser) Restore(ctx context.Context, id uint, version *uint, restoredBy *string) (*model.User, error) {
+	return &model.User{ID: id, Username: "username1", Email: "email1", Age: 60}, nil
+}
+
+func (u *MockUserNotFound) Restore(ctx context.Context, id uint, version *uint, restoredBy *string) (*model.User, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (u *MockUser) Purge(ctx context.Context, id uint, version *uint) error {
+	return nil
+}
+
+func (u *MockUserNotFound) Purge(ctx context.Context, id uint, version *uint) error {
+	return gorm.ErrRecordNotFound
+}
+
+func (u *MockUser) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
 	return true, nil
 }
 
-func (u *MockUserNotFound) IsEmailUnique(id *uint, email string) (bool, error) {
+func (u *MockUserNotFound) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
 	return false, errors.New("email already exists")
 }
 
-func (u *MockUser) IsUsernameUnique(id *uint, username string) (bool, error) {
+func (u *MockUser) IsUsernameUnique(ctx context.Context, id *uint, username string, includeDeleted bool) (bool, error) {
 	return true, nil
 }
 
-func (u *MockUserNotFound) IsUsernameUnique(id *uint, username string) (bool, error) {
+func (u *MockUserNotFound) IsUsernameUnique(ctx context.Context, id *uint, username string, includeDeleted bool) (bool, error) {
 	return false, errors.New("username already exists")
 }
 
-func (u *MockUser) Save(user repository.User) (*model.User, error) {
+func (u *MockUser) Save(ctx context.Context, user repository.User) (*model.User, error) {
 	return &model.User{
 		ID:       1,
 		Username: user.Username,
@@ -91,7 +130,7 @@ func (u *MockUser) Save(user repository.User) (*model.User, error) {
 	}, nil
 }
 
-func (u *MockUserNotFound) Save(user repository.User) (*model.User, error) {
+func (u *MockUserNotFound) Save(ctx context.Context, user repository.User) (*model.User, error) {
 	return &model.User{
 		ID:       1,
 		Username: "username1",
@@ -100,7 +139,7 @@ func (u *MockUserNotFound) Save(user repository.User) (*model.User, error) {
 	}, nil
 }
 
-func (u *MockUser) Update(user repository.User) (*model.User, error) {
+func (u *MockUser) Update(ctx context.Context, user repository.User) (*model.User, error) {
 	return &model.User{
 		ID:       user.ID,
 		Username: user.Username,
@@ -109,10 +148,18 @@ func (u *MockUser) Update(user repository.User) (*model.User, error) {
 	}, nil
 }
 
-func (u *MockUserNotFound) Update(user repository.User) (*model.User, error) {
+func (u *MockUserNotFound) Update(ctx context.Context, user repository.User) (*model.User, error) {
 	return nil, errors.New("user not found")
 }
 
+func (u *MockUser) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	return &model.ChangeMarker{Version: 5, ChangedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, nil
+}
+
+func (u *MockUserNotFound) ChangeMarker(ctx context.Context) (*model.ChangeMarker, error) {
+	return nil, errors.New("no record found")
+}
+
 func Test_userService_Get(t *testing.T) {
 	type fields struct {
 		userRepository repository.UserRepository
@@ -121,12 +168,13 @@ func Test_userService_Get(t *testing.T) {
 		id uint
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    *model.User
-		want1   int
-		wantErr bool
+		name   string
+		fields fields
+		args   args
+		want   *model.User
+		// A repository failure is reported as an internal error
+		wantInternal bool
+		wantErr      bool
 	}{
 		{
 			name: "should return user successfully",
@@ -140,7 +188,6 @@ func Test_userService_Get(t *testing.T) {
 				Email:    "email1",
 				Age:      60,
 			},
-			want1:   200,
 			wantErr: false,
 		},
 		{
@@ -148,10 +195,10 @@ func Test_userService_Get(t *testing.T) {
 			fields: fields{
 				userRepository: &MockUserNotFound{},
 			},
-			args:    args{id: 100},
-			want:    nil,
-			want1:   400,
-			wantErr: true,
+			args:         args{id: 100},
+			want:         nil,
+			wantInternal: true,
+			wantErr:      true,
 		},
 	}
 	for _, tt := range tests {
@@ -159,7 +206,7 @@ func Test_userService_Get(t *testing.T) {
 			u := &userService{
 				userRepository: tt.fields.userRepository,
 			}
-			got, got1, err := u.Get(tt.args.id)
+			got, err := u.Get(context.Background(), tt.args.id)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("userService.Get() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -167,8 +214,9 @@ func Test_userService_Get(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("userService.Get() got = %v, want %v", got, tt.want)
 			}
-			if got1 != tt.want1 {
-				t.Errorf("userService.Get() got1 = %v, want %v", got1, tt.want1)
+			var internal *InternalError
+			if errors.As(err, &internal) != tt.wantInternal {
+				t.Errorf("userService.Get() error = %v, wantInternal %v", err, tt.wantInternal)
 			}
 		})
 	}
@@ -219,7 +267,7 @@ func Test_userService_GetAll(t *testing.T) {
 			u := &userService{
 				userRepository: tt.fields.userRepository,
 			}
-			got, err := u.GetAll()
+			got, err := u.GetAll(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("userService.GetAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -231,6 +279,98 @@ func Test_userService_GetAll(t *testing.T) {
 	}
 }
 
+func Test_userService_List(t *testing.T) {
+	maxAge := 10
+	minAge := 20
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	tests := []struct {
+		name           string
+		userRepository repository.UserRepository
+		query          model.UserQuery
+		wantItems      int
+		wantNext       bool
+		wantErr        bool
+	}{
+		{name: "should return all users", userRepository: &MockUser{}, query: model.UserQuery{}, wantItems: 3},
+		{name: "should return first page with cursor", userRepository: &MockUser{}, query: model.UserQuery{Limit: 2}, wantItems: 2, wantNext: true},
+		{name: "should reject invalid limit", userRepository: &MockUser{}, query: model.UserQuery{Limit: 1000}, wantErr: true},
+		{name: "should reject invalid sort", userRepository: &MockUser{}, query: model.UserQuery{Sort: "password"}, wantErr: true},
+		{name: "should reject invalid order", userRepository: &MockUser{}, query: model.UserQuery{Order: "up"}, wantErr: true},
+		{name: "should reject invalid cursor", userRepository: &MockUser{}, query: model.UserQuery{Cursor: "abc"}, wantErr: true},
+		{name: "should reject inverted age range", userRepository: &MockUser{}, query: model.UserQuery{MinAge: &minAge, MaxAge: &maxAge}, wantErr: true},
+		{name: "should reject inverted time range", userRepository: &MockUser{}, query: model.UserQuery{CreatedAfter: &later, UpdatedBefore: &earlier}, wantErr: true},
+		{name: "should fail to return users", userRepository: &MockUserNotFound{}, query: model.UserQuery{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &userService{
+				userRepository: tt.userRepository,
+			}
+			got, err := u.List(context.Background(), tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("userService.List() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			// ValidateQuery rejects exactly the queries List finds invalid
+			var verr *ValidationError
+			if invalid := u.ValidateQuery(context.Background(), tt.query); (invalid != nil) != errors.As(err, &verr) {
+				t.Errorf("userService.ValidateQuery() error = %v, List() error = %v", invalid, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Items) != tt.wantItems {
+				t.Errorf("userService.List() items = %v, want %v", len(got.Items), tt.wantItems)
+			}
+			if (got.NextCursor != "") != tt.wantNext {
+				t.Errorf("userService.List() next cursor = %q, wantNext %v", got.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func Test_userService_ListCursor(t *testing.T) {
+	u := &userService{userRepository: &MockUser{}}
+	first, err := u.List(context.Background(), model.UserQuery{Limit: 1, Sort: "age", Order: "desc"})
+	if err != nil {
+		t.Fatalf("userService.List() error = %v", err)
+	}
+
+	opts, err := u.listOptions(model.UserQuery{Limit: 1, Sort: "age", Order: "desc", Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("userService.listOptions() error = %v", err)
+	}
+	if opts.AfterID != 1 || opts.AfterValue != 12 {
+		t.Errorf("userService.listOptions() after = (%v, %v), want (1, 12)", opts.AfterID, opts.AfterValue)
+	}
+
+	if _, err := u.listOptions(model.UserQuery{Sort: "username", Cursor: first.NextCursor}); err == nil {
+		t.Errorf("userService.listOptions() expected error for cursor of another sort")
+	}
+
+	// Forged cursors whose value does not fit the sort field are invalid
+	forged := []helper.Cursor{
+		{Sort: "age:desc", Value: map[string]interface{}{"a": 1}, ID: 1},
+		{Sort: "age:desc", Value: []int{1}, ID: 1},
+		{Sort: "age:desc", Value: "12", ID: 1},
+		{Sort: "age:desc", Value: 12.5, ID: 1},
+		{Sort: "username:asc", Value: 12, ID: 1},
+	}
+	for _, cursor := range forged {
+		token, err := helper.EncodeCursor(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort, order, _ := strings.Cut(cursor.Sort, ":")
+		_, err = u.listOptions(model.UserQuery{Sort: sort, Order: order, Cursor: token})
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Errors[0].Code != "cursor_invalid" {
+			t.Errorf("userService.listOptions() cursor %+v error = %v, want cursor_invalid", cursor, err)
+		}
+	}
+}
+
 func Test_userService_Save(t *testing.T) {
 	type fields struct {
 		userRepository repository.UserRepository
@@ -271,7 +411,7 @@ func Test_userService_Save(t *testing.T) {
 			u := &userService{
 				userRepository: tt.fields.userRepository,
 			}
-			gotUser, err := u.Save(tt.args.req)
+			gotUser, err := u.Save(context.Background(), tt.args.req)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("userService.Save() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -288,7 +428,8 @@ func Test_userService_Update(t *testing.T) {
 		userRepository repository.UserRepository
 	}
 	type args struct {
-		req model.User
+		id  uint
+		req model.UserUpdateRequest
 	}
 	tests := []struct {
 		name    string
@@ -303,8 +444,8 @@ func Test_userService_Update(t *testing.T) {
 				userRepository: &MockUser{},
 			},
 			args: args{
-				model.User{
-					ID:       1,
+				1,
+				model.UserUpdateRequest{
 					Username: "username10",
 					Email:    "email10",
 					Age:      15,
@@ -324,8 +465,8 @@ func Test_userService_Update(t *testing.T) {
 				userRepository: &MockUserNotFound{},
 			},
 			args: args{
-				model.User{
-					ID:       1,
+				1,
+				model.UserUpdateRequest{
 					Username: "username10",
 					Email:    "email10",
 					Age:      15,
@@ -340,7 +481,7 @@ func Test_userService_Update(t *testing.T) {
 			u := &userService{
 				userRepository: tt.fields.userRepository,
 			}
-			got, err := u.Update(tt.args.req)
+			got, err := u.Update(context.Background(), tt.args.id, tt.args.req, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("userService.Update() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -391,9 +532,231 @@ func Test_userService_Delete(t *testing.T) {
 			u := &userService{
 				userRepository: tt.fields.userRepository,
 			}
-			if err := u.Delete(tt.args.id); (err != nil) != tt.wantErr {
+			if err := u.Delete(context.Background(), tt.args.id, nil); (err != nil) != tt.wantErr {
 				t.Errorf("userService.Delete() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+// Repository where every email is taken
+type MockUserEmailTaken struct {
+	MockUser
+}
+
+func (u *MockUserEmailTaken) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
+	return false, nil
+}
+
+func Test_userService_ValidateConflict(t *testing.T) {
+	u := &userService{userRepository: &MockUserEmailTaken{}}
+	var conflict *ConflictError
+
+	err := u.ValidateNewUser(context.Background(), model.UserRequest{Username: "username1", Email: "email1@gmail.com", Age: 30})
+	if !errors.As(err, &conflict) || len(conflict.Errors) != 1 || conflict.Errors[0].Field != "email" {
+		t.Errorf("userService.ValidateNewUser() error = %v, want email conflict", err)
+	}
+
+	err = u.ValidateExistingUser(context.Background(), 1, model.UserUpdateRequest{Username: "username1", Email: "email1@gmail.com", Age: 30})
+	if !errors.As(err, &conflict) {
+		t.Errorf("userService.ValidateExistingUser() error = %v, want a conflict", err)
+	}
+}
+
+func Test_userService_ValidateNewUser_AllErrors(t *testing.T) {
+	u := &userService{userRepository: &MockUserEmailTaken{}}
+	var validation *ValidationError
+
+	// The taken email is listed with the other problems rather than hiding them
+	err := u.ValidateNewUser(context.Background(), model.UserRequest{Username: "", Email: "email1@gmail.com", Age: -1})
+	if !errors.As(err, &validation) {
+		t.Fatalf("userService.ValidateNewUser() error = %v, want a validation error", err)
+	}
+	codes := []string{}
+	for _, e := range validation.Errors {
+		codes = append(codes, e.Code)
+	}
+	want := []string{"email_exists", "username_required", "age_invalid"}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("userService.ValidateNewUser() codes = %v, want %v", codes, want)
+	}
+}
+
+// Repository holding a valid user whose email and username are both taken by
+// someone else
+type MockUserAllTaken struct {
+	MockUser
+}
+
+func (u *MockUserAllTaken) GetUser(ctx context.Context, id uint) (*repository.User, error) {
+	return &repository.User{ID: id, Username: "username1", Email: "email1@gmail.com", Age: 56}, nil
+}
+
+func (u *MockUserAllTaken) IsEmailUnique(ctx context.Context, id *uint, email string, includeDeleted bool) (bool, error) {
+	return false, nil
+}
+
+func (u *MockUserAllTaken) IsUsernameUnique(ctx context.Context, id *uint, username string, includeDeleted bool) (bool, error) {
+	return false, nil
+}
+
+func Test_userService_Patch(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		document  string
+		want      *model.User
+		wantCode  string
+	}{
+		{name: "should merge a single field without checking unchanged values", mediaType: "application/merge-patch+json", document: `{"age":30}`, want: &model.User{ID: 1, Username: "username1", Email: "email1@gmail.com", Age: 30}},
+		{name: "should apply a JSON Patch", mediaType: "application/json-patch+json", document: `[{"op":"test","path":"/age","value":56},{"op":"replace","path":"/age","value":57}]`, want: &model.User{ID: 1, Username: "username1", Email: "email1@gmail.com", Age: 57}},
+		{name: "should check a changed email for uniqueness", mediaType: "application/merge-patch+json", document: `{"email":"email2@gmail.com"}`, wantCode: "email_exists"},
+		{name: "should validate the merged user", mediaType: "application/merge-patch+json", document: `{"age":null}`, wantCode: "age_invalid"},
+		{name: "should reject unknown fields", mediaType: "application/merge-patch+json", document: `{"id":5}`, wantCode: "field_unknown"},
+		{name: "should reject values of the wrong type", mediaType: "application/json-patch+json", document: `[{"op":"replace","path":"/age","value":"thirty"}]`, wantCode: "type_invalid"},
+		{name: "should report a failed test operation", mediaType: "application/json-patch+json", document: `[{"op":"test","path":"/age","value":1}]`, wantCode: "patch_test_failed"},
+		{name: "should reject a malformed patch", mediaType: "application/json-patch+json", document: `{"age":30}`, wantCode: "patch_invalid"},
+		{name: "should reject a patch that replaces the user", mediaType: "application/merge-patch+json", document: `[]`, wantCode: "patch_invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &userService{userRepository: &MockUserAllTaken{}}
+			got, err := u.Patch(context.Background(), 1, model.UserPatch{MediaType: tt.mediaType, Document: []byte(tt.document)}, nil)
+			if tt.wantCode == "" {
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("userService.Patch() = %v, %v, want %v", got, err, tt.want)
+				}
+				return
+			}
+			var (
+				validation *ValidationError
+				conflict   *ConflictError
+				errs       []FieldError
+			)
+			if errors.As(err, &validation) {
+				errs = validation.Errors
+			} else if errors.As(err, &conflict) {
+				errs = conflict.Errors
+			}
+			if len(errs) != 1 || errs[0].Code != tt.wantCode {
+				t.Errorf("userService.Patch() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+// Repository where another request changes every user between GetUser and
+// Update
+type MockUserChanged struct {
+	MockUser
+}
+
+func (u *MockUserChanged) GetUser(ctx context.Context, id uint) (*repository.User, error) {
+	return &repository.User{ID: id, Username: "username1", Email: "email1@gmail.com", Age: 56, Version: 3}, nil
+}
+
+func (u *MockUserChanged) Update(ctx context.Context, user repository.User) (*model.User, error) {
+	return nil, repository.ErrVersionMismatch
+}
+
+func Test_userService_UpdateVersion(t *testing.T) {
+	stale, current := uint(2), uint(3)
+	req := model.UserUpdateRequest{Username: "username1", Email: "email1@gmail.com", Age: 30}
+	var (
+		precondition *PreconditionFailedError
+		conflict     *ConflictError
+	)
+
+	u := &userService{userRepository: &MockUserAllTaken{}}
+	if _, err := u.Update(context.Background(), 1, req, &stale); !errors.As(err, &precondition) {
+		t.Errorf("userService.Update() with a stale version error = %v, want a failed precondition", err)
+	}
+	if err := u.Delete(context.Background(), 1, &stale); !errors.As(err, &precondition) {
+		t.Errorf("userService.Delete() with a stale version error = %v, want a failed precondition", err)
+	}
+
+	// The user changes after the version was checked
+	u = &userService{userRepository: &MockUserChanged{}}
+	if _, err := u.Update(context.Background(), 1, req, &current); !errors.As(err, &precondition) {
+		t.Errorf("userService.Update() error = %v, want a failed precondition", err)
+	}
+	_, err := u.Update(context.Background(), 1, req, nil)
+	if !errors.As(err, &conflict) || conflict.Errors[0].Code != "version_conflict" {
+		t.Errorf("userService.Update() without a version error = %v, want a version conflict", err)
+	}
+}
+
+func Test_userService_Authors(t *testing.T) {
+	u := &userService{userRepository: repository.NewUserMemoryRepository()}
+	operator := model.ContextWithPrincipal(context.Background(), &model.Principal{ID: 3, Kind: model.PrincipalOperator})
+	apiKey := model.ContextWithPrincipal(context.Background(), &model.Principal{ID: 3, Kind: model.PrincipalApiKey})
+
+	created, err := u.Save(operator, model.UserRequest{Username: "username1", Email: "email1@gmail.com", Age: 30})
+	if err != nil {
+		t.Fatalf("userService.Save() error = %v", err)
+	}
+	if created.CreatedBy != "operator:3" || created.UpdatedBy != "operator:3" {
+		t.Errorf("userService.Save() authors = %q, %q, want operator:3", created.CreatedBy, created.UpdatedBy)
+	}
+
+	updated, err := u.Update(apiKey, created.ID, model.UserUpdateRequest{Username: "username1", Email: "email1@gmail.com", Age: 31}, nil)
+	if err != nil {
+		t.Fatalf("userService.Update() error = %v", err)
+	}
+	if updated.CreatedBy != "operator:3" || updated.UpdatedBy != "api_key:3" {
+		t.Errorf("userService.Update() authors = %q, %q, want operator:3, api_key:3", updated.CreatedBy, updated.UpdatedBy)
+	}
+
+	// Unauthenticated changes have no author
+	patched, err := u.Patch(context.Background(), created.ID, model.UserPatch{MediaType: "application/merge-patch+json", Document: []byte(`{"age":32}`)}, nil)
+	if err != nil {
+		t.Fatalf("userService.Patch() error = %v", err)
+	}
+	if patched.UpdatedBy != "" {
+		t.Errorf("userService.Patch() updated by = %q, want none", patched.UpdatedBy)
+	}
+}
+
+func Test_userService_SoftDelete(t *testing.T) {
+	req := model.UserRequest{Username: "username1", Email: "email1@gmail.com", Age: 30}
+	for _, reserve := range []bool{true, false} {
+		u := &userService{userRepository: repository.NewUserMemoryRepository(), deletion: config.UserDeletionConfig{ReserveValues: reserve}}
+		ctx := context.Background()
+		created, _ := u.Save(ctx, req)
+		var conflict *ConflictError
+
+		_, err := u.Restore(ctx, created.ID, nil)
+		if !errors.As(err, &conflict) || conflict.Errors[0].Code != "user_not_deleted" {
+			t.Errorf("userService.Restore() of a user that is not deleted error = %v", err)
+		}
+		if err := u.Delete(ctx, created.ID, nil); err != nil {
+			t.Fatalf("userService.Delete() error = %v", err)
+		}
+		if _, err := u.Get(ctx, created.ID); err != errUserNotFound {
+			t.Errorf("userService.Get() of a deleted user error = %v, want %v", err, errUserNotFound)
+		}
+
+		// Whether the deleted user's values can be taken depends on the config
+		err = u.ValidateNewUser(ctx, req)
+		if reserve && (!errors.As(err, &conflict) || len(conflict.Errors) != 2) {
+			t.Errorf("userService.ValidateNewUser() with reserved values error = %v", err)
+		}
+		if !reserve && err != nil {
+			t.Errorf("userService.ValidateNewUser() without reserved values error = %v", err)
+		}
+
+		restored, err := u.Restore(ctx, created.ID, nil)
+		if err != nil || restored.DeletedAt != nil {
+			t.Fatalf("userService.Restore() = %v, %v", restored, err)
+		}
+		if err := u.Purge(ctx, created.ID, &created.Version); err != errUserChanged {
+			t.Errorf("userService.Purge() with a stale version error = %v, want %v", err, errUserChanged)
+		}
+		if err := u.Purge(ctx, created.ID, nil); err != nil {
+			t.Errorf("userService.Purge() error = %v", err)
+		}
+		if _, err := u.Restore(ctx, created.ID, nil); err != errUserNotFound {
+			t.Errorf("userService.Restore() of a purged user error = %v, want %v", err, errUserNotFound)
+		}
+	}
+}
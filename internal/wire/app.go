@@ -0,0 +1,13 @@
+package wire
+
+import (
+	"atmail/internal/http"
+	"atmail/internal/service"
+)
+
+// The API server and what has to run before it starts, built from one graph
+// so that with STORAGE=memory they share the same stores
+type App struct {
+	Server    *http.ServerHTTP
+	Operators service.OperatorService
+}
@@ -4,21 +4,68 @@
 package wire
 
 import (
+	"atmail/internal/config"
 	"atmail/internal/http"
 	"atmail/internal/http/handler"
+	"atmail/internal/http/middleware"
 	"atmail/internal/http/route"
+	"atmail/internal/migration"
 	"atmail/internal/repository"
 	"atmail/internal/service"
 
 	"github.com/google/wire"
 )
 
-func Initialize() *http.ServerHTTP {
+func Initialize() (*App, func(), error) {
 	wire.Build(
+		wire.Struct(new(App), "*"),
+		config.Database,
+		repository.SelectDB,
 		route.NewUserRoute,
+		route.NewAuthRoute,
+		route.NewApiKeyRoute,
+		route.NewAuditRoute,
+		route.NewHealthRoute,
 		handler.NewUserHandler,
+		config.UserCache,
+		config.UserConcurrency,
+		config.UserDeletion,
+		handler.NewAuthHandler,
+		handler.NewApiKeyHandler,
+		handler.NewAuditHandler,
+		handler.NewHealthHandler,
+		middleware.NewAuthMiddleware,
 		service.NewUserService,
-		repository.NewUserRepository,
+		service.NewOperatorService,
+		service.NewTokenService,
+		service.NewApiKeyService,
+		service.NewAuditService,
+		service.NewHealthService,
+		config.Health,
+		repository.SelectUserRepository,
+		repository.SelectUserAuditRepository,
+		repository.SelectOperatorRepository,
+		repository.SelectRefreshTokenRepository,
+		repository.SelectApiKeyRepository,
+		repository.NewDBHealthChecker,
+		config.JWTSigningKeys,
 		http.NewServerHTTP)
-	return &http.ServerHTTP{}
+	return &App{}, nil, nil
+}
+
+func InitializeOperatorService() (service.OperatorService, func(), error) {
+	wire.Build(
+		config.Database,
+		repository.NewDB,
+		service.NewOperatorService,
+		repository.NewOperatorRepository)
+	return nil, nil, nil
+}
+
+func InitializeMigrator() (*migration.Migrator, func(), error) {
+	wire.Build(
+		config.Database,
+		repository.NewDB,
+		migration.NewMigrator)
+	return nil, nil, nil
 }
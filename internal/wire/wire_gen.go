@@ -7,20 +7,91 @@
 package wire
 
 import (
+	"atmail/internal/config"
 	"atmail/internal/http"
 	"atmail/internal/http/handler"
+	"atmail/internal/http/middleware"
 	"atmail/internal/http/route"
+	"atmail/internal/migration"
 	"atmail/internal/repository"
 	"atmail/internal/service"
 )
 
 // Injectors from wire.go:
 
-func Initialize() *http.ServerHTTP {
-	userRepository := repository.NewUserRepository()
-	userService := service.NewUserService(userRepository)
-	userHandler := handler.NewUserHandler(userService)
-	userRoute := route.NewUserRoute(userHandler)
-	serverHTTP := http.NewServerHTTP(userRoute)
-	return serverHTTP
+func Initialize() (*App, func(), error) {
+	dbConfig := config.Database()
+	db, cleanup, err := repository.SelectDB(dbConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	userRepository := repository.SelectUserRepository(db, dbConfig)
+	userDeletionConfig := config.UserDeletion()
+	userService := service.NewUserService(userRepository, userDeletionConfig)
+	userCacheConfig := config.UserCache()
+	userHandler := handler.NewUserHandler(userService, userCacheConfig)
+	operatorRepository := repository.SelectOperatorRepository(db, dbConfig)
+	operatorService := service.NewOperatorService(operatorRepository)
+	refreshTokenRepository := repository.SelectRefreshTokenRepository(db, dbConfig)
+	signingKeys := config.JWTSigningKeys()
+	tokenService := service.NewTokenService(operatorService, refreshTokenRepository, signingKeys)
+	apiKeyRepository := repository.SelectApiKeyRepository(db, dbConfig)
+	apiKeyService := service.NewApiKeyService(apiKeyRepository, operatorService)
+	authMiddleware := middleware.NewAuthMiddleware(operatorService, tokenService, apiKeyService)
+	userConcurrencyConfig := config.UserConcurrency()
+	userRoute := route.NewUserRoute(userHandler, authMiddleware, userConcurrencyConfig)
+	authHandler := handler.NewAuthHandler(tokenService)
+	authRoute := route.NewAuthRoute(authHandler)
+	apiKeyHandler := handler.NewApiKeyHandler(apiKeyService)
+	apiKeyRoute := route.NewApiKeyRoute(apiKeyHandler, authMiddleware)
+	userAuditRepository := repository.SelectUserAuditRepository(db, dbConfig, userRepository)
+	auditService := service.NewAuditService(userAuditRepository)
+	auditHandler := handler.NewAuditHandler(auditService)
+	auditRoute := route.NewAuditRoute(auditHandler, authMiddleware)
+	dbHealthChecker := repository.NewDBHealthChecker(db)
+	healthConfig := config.Health()
+	healthService := service.NewHealthService(dbHealthChecker, healthConfig)
+	healthHandler := handler.NewHealthHandler(healthService)
+	healthRoute := route.NewHealthRoute(healthHandler)
+	serverHTTP, err := http.NewServerHTTP(userRoute, authRoute, apiKeyRoute, auditRoute, healthRoute, healthService)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	app := &App{
+		Server:    serverHTTP,
+		Operators: operatorService,
+	}
+	return app, func() {
+		cleanup()
+	}, nil
+}
+
+func InitializeOperatorService() (service.OperatorService, func(), error) {
+	dbConfig := config.Database()
+	db, cleanup, err := repository.NewDB(dbConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	operatorRepository := repository.NewOperatorRepository(db, dbConfig)
+	operatorService := service.NewOperatorService(operatorRepository)
+	return operatorService, func() {
+		cleanup()
+	}, nil
+}
+
+func InitializeMigrator() (*migration.Migrator, func(), error) {
+	dbConfig := config.Database()
+	db, cleanup, err := repository.NewDB(dbConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return migrator, func() {
+		cleanup()
+	}, nil
 }
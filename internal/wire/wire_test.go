@@ -0,0 +1,29 @@
+package wire
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+// With STORAGE=memory the server starts without a reachable database, and
+// the bootstrapped operator lives in the store the server uses
+func TestInitialize_Memory(t *testing.T) {
+	g := gomega.NewWithT(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("DB_DRIVER", "mysql")
+	t.Setenv("DB_DSN", "atmail:atmail@tcp(127.0.0.1:1)/atmail?timeout=1s")
+	t.Setenv("BOOTSTRAP_OPERATOR_USERNAME", "admin")
+	t.Setenv("BOOTSTRAP_OPERATOR_PASSWORD", "correct horse battery staple")
+
+	app, cleanup, err := Initialize()
+	g.Expect(err).To(gomega.BeNil())
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	g.Expect(app.Operators.Bootstrap(ctx)).To(gomega.Succeed())
+	operator, err := app.Operators.Authenticate(ctx, "admin", "correct horse battery staple")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(operator.Username).To(gomega.Equal("admin"))
+}